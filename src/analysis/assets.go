@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// A command attribute that references an asset.
+type AssetReference struct {
+	Script		int		`json:"script"`
+	Index		int		`json:"index"`
+	Command		string	`json:"command"`
+	Attribute	string	`json:"attribute"`
+}
+
+type Asset struct {
+	Path		string				`json:"path"`
+	References	[]AssetReference	`json:"references"`
+}
+
+// A reference whose path is computed at runtime and cannot be checked.
+type DynamicAsset struct {
+	Expression	string			`json:"expression"`
+	Reference	AssetReference	`json:"reference"`
+}
+
+// The assets referenced by string attributes of the scanned scripts.
+type AssetScanner struct {
+	Attributes	map[string]bool
+	Commands	map[string]bool
+	Assets		map[string]*Asset
+	Dynamic		[]DynamicAsset
+}
+
+// Returns a scanner of the given attribute names. If commands is not empty,
+// only those commands are scanned.
+func NewAssetScanner(attributes []string, commands []string) *AssetScanner {
+	s := &AssetScanner{}
+	s.Attributes = make(map[string]bool)
+	for _, name := range attributes {
+		s.Attributes[name] = true
+	}
+
+	s.Commands = make(map[string]bool)
+	for _, name := range commands {
+		s.Commands[name] = true
+	}
+
+	s.Assets = make(map[string]*Asset)
+	return s
+}
+
+func (s *AssetScanner) AddScript(scriptIndex int, script yuris.Script, compiler yuris.CompilerDefinition) {
+	for i, command := range script.Commands {
+		name := compiler.Commands[command.Id]
+		if len(s.Commands) > 0 && !s.Commands[name] {
+			continue
+		}
+
+		for _, attribute := range script.CommandAttributes(command) {
+			attrName := compiler.Definition(command.Id, attribute.Id).Name
+			if !s.Attributes[attrName] {
+				continue
+			}
+
+			ref := AssetReference{scriptIndex, i, name, attrName}
+			tokens := yuris.Tokenize(attribute.Bytes)
+			if len(tokens) != 1 || tokens[0].Opcode != 0x4d {
+				s.Dynamic = append(s.Dynamic, DynamicAsset{attribute.Decompile(), ref})
+				continue
+			}
+
+			assetPath := strings.Trim(tokens[0].Text(), "\"")
+			asset, ok := s.Assets[assetKey(assetPath)]
+			if !ok {
+				asset = &Asset{Path: assetPath}
+				s.Assets[assetKey(assetPath)] = asset
+			}
+
+			asset.References = append(asset.References, ref)
+		}
+	}
+}
+
+// Returns the assets sorted by path.
+func (s *AssetScanner) Sorted() []*Asset {
+	assets := make([]*Asset, 0, len(s.Assets))
+	for _, asset := range s.Assets {
+		assets = append(assets, asset)
+	}
+
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].Path < assets[j].Path
+	})
+
+	return assets
+}
+
+// Compares the referenced assets with the files of a directory or a YPF
+// archive. Scripts usually omit the file extension, so paths are compared
+// without it and ignoring case. Returns the missing assets and the unused
+// files.
+func (s *AssetScanner) Check(root string) ([]*Asset, []string, error) {
+	files, err := listFiles(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make(map[string]bool)
+	for _, key := range files {
+		found[key] = true
+	}
+
+	missing := make([]*Asset, 0)
+	for _, asset := range s.Sorted() {
+		if !found[assetKey(asset.Path)] {
+			missing = append(missing, asset)
+		}
+	}
+
+	unused := make([]string, 0)
+	for file, key := range files {
+		if _, ok := s.Assets[key]; !ok {
+			unused = append(unused, file)
+		}
+	}
+	sort.Strings(unused)
+
+	return missing, unused, nil
+}
+
+// Maps the paths of the files in a directory or a YPF archive to their keys.
+func listFiles(root string) (map[string]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	if !info.IsDir() {
+		if !strings.EqualFold(filepath.Ext(root), ".ypf") {
+			return nil, errors.New(root + " is not a directory or a YPF archive")
+		}
+
+		archive, err := yuris.OpenArchive(root)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+
+		for _, entry := range archive.Entries {
+			files[strings.ReplaceAll(entry.Name, "\\", "/")] = assetKey(entry.Name)
+		}
+
+		return files, nil
+	}
+
+	err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relative, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(relative)] = assetKey(relative)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Normalizes an asset path for comparison.
+func assetKey(assetPath string) string {
+	key := strings.ToLower(strings.ReplaceAll(assetPath, "\\", "/"))
+	key = strings.TrimPrefix(path.Clean("/" + key), "/")
+
+	return strings.TrimSuffix(key, path.Ext(key))
+}
@@ -0,0 +1,169 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/damianfadri/yuris-decompiler/cfg"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type DeadCodeKind string
+
+const (
+	UnusedLabel			DeadCodeKind = "unused-label"
+	UnreachableCode		DeadCodeKind = "unreachable"
+	ConstantCondition	DeadCodeKind = "constant-condition"
+	DynamicTarget		DeadCodeKind = "dynamic-target"
+)
+
+// A finding of the dead code analysis. End is the last command of
+// unreachable code.
+type DeadCode struct {
+	Kind		DeadCodeKind	`json:"kind"`
+	Script		int				`json:"script"`
+	Index		int				`json:"index"`
+	End			int				`json:"end,omitempty"`
+	Label		string			`json:"label,omitempty"`
+	Message		string			`json:"message"`
+}
+
+func (d DeadCode) String() string {
+	return fmt.Sprintf("yst%05d:%d: %s: %s", d.Script, d.Index, d.Kind, d.Message)
+}
+
+// Finds unused labels, unreachable code and constant conditions across
+// scripts.
+type DeadCodeFinder struct {
+	labels		[]yuris.Label
+	targets		map[string]bool
+	findings	[]DeadCode
+}
+
+// Returns a finder for the labels of ysl.ybn.
+func NewDeadCodeFinder(labels []yuris.Label) *DeadCodeFinder {
+	return &DeadCodeFinder{labels, make(map[string]bool), nil}
+}
+
+func (f *DeadCodeFinder) AddScript(scriptIndex int, script yuris.Script, compiler yuris.CompilerDefinition) {
+	labels := yuris.ScriptLabels(f.labels, scriptIndex)
+	g := cfg.Build(script, compiler, labels)
+
+	for i, command := range script.Commands {
+		name := compiler.Commands[command.Id]
+		attributes := script.CommandAttributes(command)
+		switch name {
+		case "GOTO", "GOSUB":
+			if len(attributes) == 0 {
+				continue
+			}
+
+			value, err := attributes[0].Evaluate(nil)
+			if err != nil {
+				f.add(DeadCode{Kind: DynamicTarget, Script: scriptIndex, Index: i,
+					Message: fmt.Sprintf("%s to computed label %s, unused labels may be targeted by it", name, attributes[0].Decompile())})
+				continue
+			}
+
+			f.targets[yuris.LabelName(value.String())] = true
+		case "IF", "ELSE":
+			if len(attributes) == 0 {
+				continue
+			}
+
+			value, err := attributes[0].Evaluate(nil)
+			if err != nil {
+				continue
+			}
+
+			branch := "never taken"
+			if value.IsTrue() {
+				branch = "always taken"
+			}
+
+			f.add(DeadCode{Kind: ConstantCondition, Script: scriptIndex, Index: i,
+				Message: fmt.Sprintf("%s[%s] is %s", name, attributes[0].Decompile(), branch)})
+		}
+	}
+
+	// Code after GOTO, RETURN or END that no label or block end leads to.
+	// The IFBLEND and IFEND the compiler adds after a branch that ends with
+	// GOTO or RETURN are not reported.
+	reachable := g.ReachableFromLabels()
+	for i := 0; i < len(g.Blocks); i++ {
+		if reachable[g.Blocks[i]] {
+			continue
+		}
+
+		start, end := -1, -1
+		for ; i < len(g.Blocks) && !reachable[g.Blocks[i]]; i++ {
+			block := g.Blocks[i]
+			for j, name := range block.Commands {
+				if name == "IFBLEND" || name == "IFEND" {
+					continue
+				}
+
+				if start < 0 {
+					start = block.Start + j
+				}
+				end = block.Start + j
+			}
+		}
+
+		if start < 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("commands %d to %d are unreachable", start, end)
+		if start > 0 {
+			message += " after " + compiler.Commands[script.Commands[start - 1].Id]
+		}
+
+		f.add(DeadCode{Kind: UnreachableCode, Script: scriptIndex, Index: start, End: end, Message: message})
+	}
+}
+
+func (f *DeadCodeFinder) add(finding DeadCode) {
+	f.findings = append(f.findings, finding)
+}
+
+// Returns the offset of the first label of yst00000, where the engine starts.
+func (f *DeadCodeFinder) entryOffset() (int, bool) {
+	offset, ok := 0, false
+	for _, label := range f.labels {
+		if label.ScriptIndex == 0 && (!ok || label.Offset < offset) {
+			offset, ok = label.Offset, true
+		}
+	}
+
+	return offset, ok
+}
+
+// Returns the findings sorted by location, including the labels that no
+// GOTO or GOSUB of the added scripts targets. The entry labels, at the start
+// of yst00000, are never reported as unused.
+func (f *DeadCodeFinder) Findings() []DeadCode {
+	findings := append([]DeadCode{}, f.findings...)
+	entry, hasEntry := f.entryOffset()
+	for _, label := range f.labels {
+		if f.targets[label.Name] {
+			continue
+		}
+
+		if hasEntry && label.ScriptIndex == 0 && label.Offset == entry {
+			continue
+		}
+
+		findings = append(findings, DeadCode{Kind: UnusedLabel, Script: int(label.ScriptIndex), Index: label.Offset,
+			Label: label.Name, Message: fmt.Sprintf("label %s is never targeted by GOTO or GOSUB", label.Name)})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Script != findings[j].Script {
+			return findings[i].Script < findings[j].Script
+		}
+		return findings[i].Index < findings[j].Index
+	})
+
+	return findings
+}
@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestUnusedLabels(t *testing.T) {
+	tests := []struct {
+		name		string
+		labels		[]yuris.Label
+		want		[]string
+	}{
+		{
+			name:	"entry label",
+			labels:	[]yuris.Label{
+				{Name: "START", ScriptIndex: 0, Offset: 0},
+				{Name: "ORPHAN", ScriptIndex: 0, Offset: 3},
+			},
+			want:	[]string{"ORPHAN"},
+		},
+		{
+			name:	"entry labels after the first command",
+			labels:	[]yuris.Label{
+				{Name: "ORPHAN", ScriptIndex: 1, Offset: 0},
+				{Name: "START", ScriptIndex: 0, Offset: 2},
+				{Name: "ALIAS", ScriptIndex: 0, Offset: 2},
+			},
+			want:	[]string{"ORPHAN"},
+		},
+		{
+			name:	"no labels in yst00000",
+			labels:	[]yuris.Label{{Name: "ORPHAN", ScriptIndex: 1, Offset: 0}},
+			want:	[]string{"ORPHAN"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := make([]string, 0)
+			for _, finding := range NewDeadCodeFinder(test.labels).Findings() {
+				if finding.Kind == UnusedLabel {
+					names = append(names, finding.Label)
+				}
+			}
+
+			if !reflect.DeepEqual(names, test.want) {
+				t.Errorf("unused labels = %v, want %v", names, test.want)
+			}
+		})
+	}
+}
+
+func TestUnreachableCode(t *testing.T) {
+	tests := []struct {
+		name		string
+		commands	[]yuristest.CommandSpec
+		want		[]string
+	}{
+		{
+			name:	"after RETURN",
+			commands:	[]yuristest.CommandSpec{
+				yuristest.Command("RETURN"),
+				yuristest.Command("_"),
+				yuristest.Command("_"),
+			},
+			want:	[]string{"yst00000:1: unreachable: commands 1 to 2 are unreachable after RETURN"},
+		},
+		{
+			name:	"block ends after every branch returns",
+			commands:	[]yuristest.CommandSpec{
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("RETURN"),
+				yuristest.Command("IFBLEND"),
+				yuristest.Command("ELSE"),
+				yuristest.Command("GOTO", yuristest.LabelRef("START")),
+				yuristest.Command("IFEND"),
+			},
+			want:	[]string{},
+		},
+		{
+			name:	"code after the block ends",
+			commands:	[]yuristest.CommandSpec{
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("RETURN"),
+				yuristest.Command("IFBLEND"),
+				yuristest.Command("ELSE"),
+				yuristest.Command("RETURN"),
+				yuristest.Command("IFEND"),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+			},
+			want:	[]string{"yst00000:6: unreachable: commands 6 to 6 are unreachable after IFEND"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, compiler := yuristest.NewScript(test.commands...)
+			finder := NewDeadCodeFinder([]yuris.Label{{Name: "START", ScriptIndex: 0, Offset: 0}})
+			finder.AddScript(0, script, compiler)
+
+			findings := make([]string, 0)
+			for _, finding := range finder.Findings() {
+				if finding.Kind == UnreachableCode {
+					findings = append(findings, finding.String())
+				}
+			}
+
+			if !reflect.DeepEqual(findings, test.want) {
+				t.Errorf("unreachable code = %q, want %q", findings, test.want)
+			}
+		})
+	}
+}
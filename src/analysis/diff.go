@@ -0,0 +1,303 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type ChangeKind string
+
+const (
+	Added			ChangeKind = "added"
+	Removed			ChangeKind = "removed"
+	TextChanged		ChangeKind = "text"
+	ArgsChanged		ChangeKind = "arguments"
+)
+
+// The decompiled lines of a script from a label up to the next label.
+// Lines before the first label of a script belong to a segment named after
+// the script.
+type Segment struct {
+	Name		string
+	Script		int
+	Lines		[]string
+}
+
+type LineChange struct {
+	Kind		ChangeKind	`json:"kind"`
+	Old			string		`json:"old,omitempty"`
+	New			string		`json:"new,omitempty"`
+}
+
+// The differences of a label between two versions. Kind is empty if the
+// label exists in both.
+type LabelDiff struct {
+	Name		string			`json:"name"`
+	Kind		ChangeKind		`json:"kind,omitempty"`
+	OldScript	int				`json:"oldScript"`
+	NewScript	int				`json:"newScript"`
+	Changes		[]LineChange	`json:"changes"`
+}
+
+// Splits decompiled lines into one segment per label, dropping braces and
+// keeping the indentation of nested lines.
+func Segments(lines []yuris.Line, scriptIndex int) []Segment {
+	segments := make([]Segment, 0)
+	current := Segment{Name: fmt.Sprintf("yst%05d", scriptIndex), Script: scriptIndex}
+	isLabel := false
+
+	for _, line := range lines {
+		for _, text := range strings.Split(line.ToString(0), "\n") {
+			trimmed := strings.TrimSpace(text)
+			if trimmed == "" || trimmed == "{" || trimmed == "}" {
+				continue
+			}
+
+			if strings.HasPrefix(trimmed, "#=") {
+				if isLabel || len(current.Lines) > 0 {
+					segments = append(segments, current)
+				}
+
+				name := strings.TrimPrefix(trimmed, "#=")
+				if comment := strings.Index(name, " //"); comment >= 0 {
+					name = name[:comment]
+				}
+
+				current = Segment{Name: name, Script: scriptIndex}
+				isLabel = true
+				continue
+			}
+
+			current.Lines = append(current.Lines, strings.TrimRight(text, " \r"))
+		}
+	}
+
+	if isLabel || len(current.Lines) > 0 {
+		segments = append(segments, current)
+	}
+
+	return segments
+}
+
+// Compares the segments of two versions by label name. Replaced lines of
+// the same command are reported as text changes if the command is one of
+// the text commands, and as argument changes otherwise.
+func DiffSegments(old []Segment, new []Segment, textCommands []string) []LabelDiff {
+	isText := make(map[string]bool)
+	for _, name := range textCommands {
+		isText[name] = true
+	}
+
+	oldByName := make(map[string]Segment)
+	for _, segment := range old {
+		oldByName[segment.Name] = segment
+	}
+
+	newByName := make(map[string]Segment)
+	for _, segment := range new {
+		newByName[segment.Name] = segment
+	}
+
+	diffs := make([]LabelDiff, 0)
+	for _, segment := range old {
+		if _, ok := newByName[segment.Name]; !ok {
+			diff := LabelDiff{Name: segment.Name, Kind: Removed, OldScript: segment.Script, NewScript: -1}
+			for _, line := range segment.Lines {
+				diff.Changes = append(diff.Changes, LineChange{Kind: Removed, Old: line})
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+
+	for _, segment := range new {
+		oldSegment, ok := oldByName[segment.Name]
+		if !ok {
+			diff := LabelDiff{Name: segment.Name, Kind: Added, OldScript: -1, NewScript: segment.Script}
+			for _, line := range segment.Lines {
+				diff.Changes = append(diff.Changes, LineChange{Kind: Added, New: line})
+			}
+			diffs = append(diffs, diff)
+			continue
+		}
+
+		changes := diffLines(oldSegment.Lines, segment.Lines, isText)
+		if len(changes) > 0 || oldSegment.Script != segment.Script {
+			diffs = append(diffs, LabelDiff{segment.Name, "", oldSegment.Script, segment.Script, changes})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffScript(diffs[i]) < diffScript(diffs[j])
+	})
+
+	return diffs
+}
+
+func diffScript(diff LabelDiff) int {
+	if diff.NewScript >= 0 {
+		return diff.NewScript
+	}
+	return diff.OldScript
+}
+
+// Returns the changes between two sequences of lines, from their shortest
+// edit script.
+func diffLines(old []string, new []string, isText map[string]bool) []LineChange {
+	changes := make([]LineChange, 0)
+	removed := make([]string, 0)
+	added := make([]string, 0)
+
+	// Pairs the pending removed and added lines of the same command.
+	flush := func() {
+		for len(removed) > 0 || len(added) > 0 {
+			switch {
+			case len(removed) > 0 && len(added) > 0 && commandOf(removed[0]) == commandOf(added[0]):
+				kind := ArgsChanged
+				if isText[commandOf(removed[0])] {
+					kind = TextChanged
+				}
+				changes = append(changes, LineChange{kind, removed[0], added[0]})
+				removed = removed[1:]
+				added = added[1:]
+			case len(removed) > 0:
+				changes = append(changes, LineChange{Kind: Removed, Old: removed[0]})
+				removed = removed[1:]
+			default:
+				changes = append(changes, LineChange{Kind: Added, New: added[0]})
+				added = added[1:]
+			}
+		}
+	}
+
+	for _, edit := range editScript(old, new) {
+		switch edit.kind {
+		case Removed:
+			removed = append(removed, edit.line)
+		case Added:
+			added = append(added, edit.line)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return changes
+}
+
+// A line of an edit script. Kind is empty for a line kept as it is.
+type lineEdit struct {
+	kind		ChangeKind
+	line		string
+}
+
+// Returns the shortest edit script that turns the old lines into the new
+// ones, using the linear space variant of Myers' algorithm. It takes
+// O((N+M)D) time and O(N+M) memory for D changed lines.
+func editScript(old []string, new []string) []lineEdit {
+	return appendEdits(make([]lineEdit, 0, len(old) + len(new)), old, new)
+}
+
+func appendEdits(edits []lineEdit, old []string, new []string) []lineEdit {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		edits = append(edits, lineEdit{"", old[prefix]})
+		prefix++
+	}
+	old, new = old[prefix:], new[prefix:]
+
+	suffix := 0
+	for suffix < len(old) && suffix < len(new) && old[len(old) - 1 - suffix] == new[len(new) - 1 - suffix] {
+		suffix++
+	}
+	common := old[len(old) - suffix:]
+	old, new = old[:len(old) - suffix], new[:len(new) - suffix]
+
+	switch {
+	case len(old) == 0:
+		for _, line := range new {
+			edits = append(edits, lineEdit{Added, line})
+		}
+	case len(new) == 0:
+		for _, line := range old {
+			edits = append(edits, lineEdit{Removed, line})
+		}
+	default:
+		// Both start and end with a change, so there are at least two and
+		// each half has fewer.
+		x, y, u, v := middleSnake(old, new)
+		edits = appendEdits(edits, old[:x], new[:y])
+		for _, line := range old[x:u] {
+			edits = append(edits, lineEdit{"", line})
+		}
+		edits = appendEdits(edits, old[u:], new[v:])
+	}
+
+	for _, line := range common {
+		edits = append(edits, lineEdit{"", line})
+	}
+
+	return edits
+}
+
+// Returns the start and end of the common run in the middle of a shortest
+// edit path, found by searching from both ends at once. The diagonals are
+// k = x - y from the start and x - y counted from the end.
+func middleSnake(a []string, b []string) (int, int, int, int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	max := (n + m + 1) / 2
+	offset := max + 1
+	forward := make([]int, 2 * offset + 1)
+	backward := make([]int, 2 * offset + 1)
+
+	// Returns the furthest x on diagonal k after step d, and where its run
+	// of equal lines starts.
+	step := func(v []int, d int, k int, equal func(x int, y int) bool) (int, int) {
+		x := v[offset + k - 1] + 1
+		if k == -d || (k != d && v[offset + k - 1] < v[offset + k + 1]) {
+			x = v[offset + k + 1]
+		}
+
+		start := x
+		for x < n && x - k < m && equal(x, x - k) {
+			x++
+		}
+		v[offset + k] = x
+
+		return start, x
+	}
+
+	forwardEqual := func(x int, y int) bool { return a[x] == b[y] }
+	backwardEqual := func(x int, y int) bool { return a[n - 1 - x] == b[m - 1 - y] }
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			start, x := step(forward, d, k, forwardEqual)
+			if c := delta - k; delta % 2 != 0 && -d < c && c < d && x + backward[offset + c] >= n {
+				return start, start - k, x, x - k
+			}
+		}
+
+		for c := -d; c <= d; c += 2 {
+			start, x := step(backward, d, c, backwardEqual)
+			if k := delta - c; delta % 2 == 0 && -d <= k && k <= d && x + forward[offset + k] >= n {
+				return n - x, m - (x - c), n - start, m - (start - c)
+			}
+		}
+	}
+
+	return n, m, n, m
+}
+
+// Returns the command of a decompiled line, or LET for assignments.
+func commandOf(line string) string {
+	line = strings.TrimSpace(line)
+	if open := strings.Index(line, "["); open > 0 && !strings.ContainsAny(line[:open], " =") {
+		return line[:open]
+	}
+
+	return "LET"
+}
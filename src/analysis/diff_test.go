@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestSegments(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("_"),
+		yuristest.Command("IF", yuristest.Variable(1)),
+		yuristest.Command("A"),
+		yuristest.Command("IFEND"),
+		yuristest.Command("B"),
+		yuristest.Command("RETURN"),
+	)
+	labels := []yuris.Label{{Name: "FIRST", Offset: 1}, {Name: "EMPTY", Offset: 4}, {Name: "SECOND", Offset: 4}}
+	lines, _ := yuris.Decompile(script, compiler, labels, yuris.Options{})
+
+	want := []Segment{
+		{Name: "yst00002", Script: 2, Lines: []string{"_[]"}},
+		{Name: "FIRST", Script: 2, Lines: []string{"IF[@var1]", "  A[]", "IFEND[]"}},
+		{Name: "EMPTY", Script: 2},
+		{Name: "SECOND", Script: 2, Lines: []string{"  B[]", "  RETURN[]"}},
+	}
+	if got := Segments(lines, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name		string
+		old			string
+		new			string
+		want		[]LineChange
+	}{
+		{name: "same", old: "A[] B[]", new: "A[] B[]", want: []LineChange{}},
+		{name: "empty", old: "", new: "", want: []LineChange{}},
+		{
+			name:	"added",
+			old:	"A[] C[]",
+			new:	"A[] B[] C[]",
+			want:	[]LineChange{{Kind: Added, New: "B[]"}},
+		},
+		{
+			name:	"removed",
+			old:	"A[] B[] C[]",
+			new:	"A[] C[]",
+			want:	[]LineChange{{Kind: Removed, Old: "B[]"}},
+		},
+		{
+			name:	"text changed",
+			old:	"A[] _[x] C[]",
+			new:	"A[] _[y] C[]",
+			want:	[]LineChange{{TextChanged, "_[x]", "_[y]"}},
+		},
+		{
+			name:	"arguments changed",
+			old:	"A[] B[X=1] C[]",
+			new:	"A[] B[X=2] C[]",
+			want:	[]LineChange{{ArgsChanged, "B[X=1]", "B[X=2]"}},
+		},
+		{
+			name:	"replaced by another command",
+			old:	"A[] B[]",
+			new:	"A[] C[]",
+			want:	[]LineChange{{Kind: Removed, Old: "B[]"}, {Kind: Added, New: "C[]"}},
+		},
+		{
+			name:	"all replaced",
+			old:	"A[] B[]",
+			new:	"C[]",
+			want:	[]LineChange{{Kind: Removed, Old: "A[]"}, {Kind: Removed, Old: "B[]"}, {Kind: Added, New: "C[]"}},
+		},
+		{
+			name:	"moved",
+			old:	"A[] B[] C[] D[]",
+			new:	"B[] C[] D[] A[]",
+			want:	[]LineChange{{Kind: Removed, Old: "A[]"}, {Kind: Added, New: "A[]"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diffLines(strings.Fields(test.old), strings.Fields(test.new), map[string]bool{"_": true})
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+// Checks that the edit script turns the old lines into the new ones with as
+// few edits as the longest common subsequence allows.
+func TestEditScript(t *testing.T) {
+	tests := [][2]string{
+		{"", "abc"},
+		{"abc", ""},
+		{"abcabba", "cbabac"},
+		{"xaxbxcx", "abc"},
+		{"abcdefg", "gfedcba"},
+		{"aaaaab", "baaaaa"},
+		{"abcxyzabc", "abcabc"},
+	}
+
+	for _, test := range tests {
+		old := strings.Split(test[0], "")
+		new := strings.Split(test[1], "")
+
+		edits := editScript(old, new)
+		gotOld, gotNew := make([]string, 0), make([]string, 0)
+		changed := 0
+		for _, edit := range edits {
+			if edit.kind != Added {
+				gotOld = append(gotOld, edit.line)
+			}
+			if edit.kind != Removed {
+				gotNew = append(gotNew, edit.line)
+			}
+			if edit.kind != "" {
+				changed++
+			}
+		}
+
+		if !reflect.DeepEqual(gotOld, old) || !reflect.DeepEqual(gotNew, new) {
+			t.Errorf("%q to %q: edits %+v do not give both versions", test[0], test[1], edits)
+		}
+
+		if want := len(old) + len(new) - 2 * lcsLength(old, new); changed != want {
+			t.Errorf("%q to %q: %d edits, want %d", test[0], test[1], changed, want)
+		}
+	}
+}
+
+func lcsLength(a []string, b []string) int {
+	lengths := make([][]int, len(a) + 1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b) + 1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i + 1][j + 1] + 1
+			case lengths[i + 1][j] >= lengths[i][j + 1]:
+				lengths[i][j] = lengths[i + 1][j]
+			default:
+				lengths[i][j] = lengths[i][j + 1]
+			}
+		}
+	}
+
+	return lengths[0][0]
+}
@@ -0,0 +1,291 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// A scene of the story, starting at a label or at the start of a script.
+type Scene struct {
+	Name		string		`json:"name"`
+	Script		int			`json:"script"`
+	Offset		int			`json:"offset"`
+	Choices		[][]string	`json:"choices,omitempty"`
+	Flags		[]string	`json:"flags,omitempty"`
+}
+
+// A transition between scenes. Conditions are the enclosing IF conditions,
+// outermost first, and Choice is the text of the option that leads to it.
+type RouteEdge struct {
+	From		string		`json:"from"`
+	To			string		`json:"to"`
+	Kind		string		`json:"kind"`
+	Choice		string		`json:"choice,omitempty"`
+	Conditions	[]string	`json:"conditions,omitempty"`
+}
+
+// The branching structure of the story across scripts.
+type RouteGraph struct {
+	Scenes		[]*Scene	`json:"scenes"`
+	Edges		[]RouteEdge	`json:"edges"`
+
+	choiceCommands	map[string]bool
+	symbols			*yuris.Symbols
+	scenes			map[string]*Scene
+	edges			map[string]bool
+}
+
+// An open IF statement, with the conditions of its previous branches.
+type openIf struct {
+	previous	[]string
+	current		string
+}
+
+// The script currently being added.
+type routeState struct {
+	scene		*Scene
+	ifs			[]openIf
+	choice		[]string
+	isDead		bool
+}
+
+var choiceIndexPattern = regexp.MustCompile(`==\s*(\d+)$`)
+
+func NewRouteGraph(choiceCommands []string, symbols *yuris.Symbols) *RouteGraph {
+	g := &RouteGraph{}
+	g.choiceCommands = make(map[string]bool)
+	for _, name := range choiceCommands {
+		g.choiceCommands[name] = true
+	}
+
+	g.symbols = symbols
+	g.scenes = make(map[string]*Scene)
+	g.edges = make(map[string]bool)
+
+	return g
+}
+
+// Adds the scenes and transitions of a script. Labels must come from
+// yuris.ScriptLabels.
+func (g *RouteGraph) AddScript(scriptIndex int, script yuris.Script, compiler yuris.CompilerDefinition, labels []yuris.Label) {
+	state := routeState{}
+	if len(script.Commands) > 0 && (len(labels) == 0 || labels[0].Offset > 0) {
+		state.scene = g.scene(fmt.Sprintf("yst%05d", scriptIndex))
+		state.scene.Script = scriptIndex
+	}
+
+	labelIndex := 0
+	for i, command := range script.Commands {
+		for labelIndex < len(labels) && labels[labelIndex].Offset <= i {
+			g.enterScene(&state, labels[labelIndex])
+			labelIndex++
+		}
+
+		g.addCommand(&state, script, compiler, command)
+	}
+
+	for labelIndex < len(labels) {
+		g.enterScene(&state, labels[labelIndex])
+		labelIndex++
+	}
+}
+
+func (g *RouteGraph) enterScene(state *routeState, label yuris.Label) {
+	scene := g.scene(g.symbols.LabelName(label.Name))
+	scene.Script = int(label.ScriptIndex)
+	scene.Offset = label.Offset
+
+	if state.scene != nil && !state.isDead {
+		g.addEdge(state, scene.Name, "next")
+	}
+
+	state.scene = scene
+	state.choice = nil
+	state.isDead = false
+}
+
+func (g *RouteGraph) addCommand(state *routeState, script yuris.Script, compiler yuris.CompilerDefinition, command yuris.Command) {
+	name := compiler.Commands[command.Id]
+	attributes := script.CommandAttributes(command)
+
+	if state.scene == nil {
+		return
+	}
+
+	if g.choiceCommands[name] {
+		options := make([]string, 0)
+		for _, attribute := range attributes {
+			for _, token := range yuris.Tokenize(attribute.Bytes) {
+				if token.Opcode == 0x4d {
+					options = append(options, strings.Trim(token.Text(), "\""))
+				}
+			}
+		}
+
+		state.scene.Choices = append(state.scene.Choices, options)
+		state.choice = options
+		return
+	}
+
+	switch name {
+	case "IF":
+		state.ifs = append(state.ifs, openIf{nil, g.condition(attributes)})
+	case "ELSE":
+		if len(state.ifs) == 0 {
+			return
+		}
+
+		top := &state.ifs[len(state.ifs) - 1]
+		top.previous = append(top.previous, top.current)
+		top.current = g.condition(attributes)
+	case "IFEND":
+		if len(state.ifs) > 0 {
+			state.ifs = state.ifs[:len(state.ifs) - 1]
+		}
+	case "LET":
+		if len(attributes) < 2 {
+			return
+		}
+
+		target := attributes[0].DecompileWith(g.symbols)
+		flag := fmt.Sprintf("%s %s %s", target, yuris.AssignmentOperator(target, attributes[0].Type[1]), attributes[1].DecompileWith(g.symbols))
+		state.scene.Flags = append(state.scene.Flags, flag)
+	case "GOTO", "GOSUB":
+		if len(attributes) == 0 {
+			return
+		}
+
+		target := g.symbols.LabelName(yuris.LabelName(attributes[0].Decompile()))
+		g.addEdge(state, target, strings.ToLower(name))
+
+		if name == "GOTO" && len(state.ifs) == 0 {
+			state.isDead = true
+		}
+	case "RETURN", "END":
+		if len(state.ifs) == 0 {
+			state.isDead = true
+		}
+	}
+}
+
+// Returns the condition of an IF or ELSE attribute. ELSE without a condition
+// returns an empty string.
+func (g *RouteGraph) condition(attributes []yuris.Attribute) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+
+	return attributes[0].DecompileWith(g.symbols)
+}
+
+// Returns the conditions that must hold to reach the current command.
+func (state *routeState) conditions() []string {
+	conditions := make([]string, 0)
+	for _, open := range state.ifs {
+		for _, previous := range open.previous {
+			conditions = append(conditions, "!(" + previous + ")")
+		}
+
+		if open.current != "" {
+			conditions = append(conditions, open.current)
+		}
+	}
+
+	return conditions
+}
+
+// Returns the text of the option chosen before a conditional transition. The
+// option is matched by the innermost condition comparing to its 1-based
+// number, otherwise every option is listed.
+func (state *routeState) choiceText(conditions []string) string {
+	if len(state.choice) == 0 || len(conditions) == 0 {
+		return ""
+	}
+
+	submatch := choiceIndexPattern.FindStringSubmatch(conditions[len(conditions) - 1])
+	if len(submatch) == 2 {
+		number, err := strconv.Atoi(submatch[1])
+		if err == nil && number >= 1 && number <= len(state.choice) {
+			return state.choice[number - 1]
+		}
+	}
+
+	return strings.Join(state.choice, " / ")
+}
+
+func (g *RouteGraph) addEdge(state *routeState, target string, kind string) {
+	g.scene(target)
+
+	edge := RouteEdge{From: state.scene.Name, To: target, Kind: kind}
+	edge.Conditions = state.conditions()
+	edge.Choice = state.choiceText(edge.Conditions)
+
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", edge.From, edge.To, edge.Kind, edge.Choice, strings.Join(edge.Conditions, "\x00"))
+	if g.edges[key] {
+		return
+	}
+
+	g.edges[key] = true
+	g.Edges = append(g.Edges, edge)
+}
+
+// Returns the scene with the given name, adding it if needed. Scenes that
+// are only referenced have script -1.
+func (g *RouteGraph) scene(name string) *Scene {
+	scene, ok := g.scenes[name]
+	if !ok {
+		scene = &Scene{Name: name, Script: -1}
+		g.scenes[name] = scene
+		g.Scenes = append(g.Scenes, scene)
+	}
+
+	return scene
+}
+
+// Renders the graph in Graphviz DOT format.
+func (g *RouteGraph) ToDot() string {
+	sb := utils.NewStringBuilder()
+
+	sb.Append("digraph routes {\n")
+	sb.Append("  node [shape=box fontname=monospace];\n")
+
+	for _, scene := range g.Scenes {
+		text := utils.EscapeDot(scene.Name) + "\\l"
+		if scene.Script >= 0 {
+			text += fmt.Sprintf("yst%05d:%d\\l", scene.Script, scene.Offset)
+		}
+		for _, flag := range scene.Flags {
+			text += utils.EscapeDot(flag) + "\\l"
+		}
+
+		sb.Append(fmt.Sprintf("  %s [label=\"%s\"];\n", utils.QuoteDot(scene.Name), text))
+	}
+
+	for _, edge := range g.Edges {
+		text := edge.Kind
+		if edge.Choice != "" {
+			text += "\\n" + utils.EscapeDot(edge.Choice)
+		}
+		for _, condition := range edge.Conditions {
+			text += "\\n[" + utils.EscapeDot(condition) + "]"
+		}
+
+		style := ""
+		switch edge.Kind {
+		case "next":
+			style = " style=dashed"
+		case "gosub":
+			style = " style=dotted"
+		}
+
+		sb.Append(fmt.Sprintf("  %s -> %s [label=\"%s\"%s];\n", utils.QuoteDot(edge.From), utils.QuoteDot(edge.To), text, style))
+	}
+
+	sb.Append("}\n")
+	return sb.ToString()
+}
@@ -0,0 +1,168 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// A block enclosing the lines being searched, such as IF or LOOP.
+type Context struct {
+	Command		string	`json:"command"`
+	Condition	string	`json:"condition"`
+}
+
+// Requires an enclosing block of the given command whose condition matches
+// the pattern. A nil pattern matches any condition.
+type ContextQuery struct {
+	Command		string
+	Condition	*regexp.Regexp
+}
+
+// Conditions on the decompiled lines to find. Empty fields match anything.
+type Query struct {
+	Command		string
+	Attribute	string
+	Value		*regexp.Regexp
+	Label		string
+	Inside		[]ContextQuery
+}
+
+type SearchMatch struct {
+	Script		int			`json:"script"`
+	Label		string		`json:"label"`
+	Index		int			`json:"index"`
+	Line		string		`json:"line"`
+	Context		[]Context	`json:"context,omitempty"`
+}
+
+type searchState struct {
+	script		int
+	label		string
+	context		[]Context
+}
+
+// Returns the lines of a decompiled script matching the query. Each branch
+// of an IF chain is matched as its own IF or ELSE line.
+func Search(lines []yuris.Line, scriptIndex int, query Query) []SearchMatch {
+	matches := make([]SearchMatch, 0)
+	search(lines, &searchState{script: scriptIndex}, query, &matches)
+
+	return matches
+}
+
+func search(lines []yuris.Line, state *searchState, query Query, matches *[]SearchMatch) {
+	for _, line := range lines {
+		if line.Command == "IF" && line.Branches != nil {
+			branches := append([]yuris.Branch{}, line.Branches...)
+			if line.Else != nil {
+				branches = append(branches, *line.Else)
+			}
+
+			for i, branch := range branches {
+				if branch.Index < 0 {
+					// The IF is outside of the decompiled lines.
+					search(branch.Body, state, query, matches)
+					continue
+				}
+
+				branchLine := yuris.Line{Index: branch.Index, Command: "ELSE"}
+				if i == 0 {
+					branchLine.Command = "IF"
+				}
+				if branch.Name != "" {
+					branchLine.Names = []string{branch.Name}
+					branchLine.Arguments = []string{branch.Condition}
+				}
+
+				state.match(branchLine, query, matches)
+				state.nested(Context{"IF", branch.Condition}, branch.Body, query, matches)
+			}
+			continue
+		}
+
+		if line.Command == "LABEL" && len(line.Arguments) > 0 {
+			// A label marker names itself and the lines that follow it, and
+			// a label block the lines inside it.
+			outer := state.label
+			state.label = line.Arguments[0]
+			state.match(line, query, matches)
+			if len(line.Children) == 0 {
+				continue
+			}
+
+			search(line.Children, state, query, matches)
+			state.label = outer
+			continue
+		}
+
+		state.match(line, query, matches)
+		if len(line.Children) > 0 {
+			condition := ""
+			if len(line.Arguments) > 0 {
+				condition = line.Arguments[0]
+			}
+			state.nested(Context{line.Command, condition}, line.Children, query, matches)
+		}
+	}
+}
+
+func (state *searchState) nested(context Context, lines []yuris.Line, query Query, matches *[]SearchMatch) {
+	state.context = append(state.context, context)
+	search(lines, state, query, matches)
+	state.context = state.context[:len(state.context) - 1]
+}
+
+func (state *searchState) match(line yuris.Line, query Query, matches *[]SearchMatch) {
+	if query.Command != "" && line.Command != query.Command {
+		return
+	}
+
+	if query.Label != "" && state.label != query.Label {
+		return
+	}
+
+	if query.Attribute != "" || query.Value != nil {
+		found := false
+		for i, name := range line.Names {
+			if query.Attribute != "" && name != query.Attribute {
+				continue
+			}
+
+			if i < len(line.Arguments) && (query.Value == nil || query.Value.MatchString(strings.Trim(line.Arguments[i], "\""))) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return
+		}
+	}
+
+	for _, inside := range query.Inside {
+		if !state.isInside(inside) {
+			return
+		}
+	}
+
+	match := SearchMatch{state.script, state.label, line.Index, "", nil}
+	match.Line = strings.TrimSpace(line.ToStringSingle(0))
+	match.Context = append(match.Context, state.context...)
+	*matches = append(*matches, match)
+}
+
+func (state *searchState) isInside(query ContextQuery) bool {
+	for _, context := range state.context {
+		if context.Command != query.Command {
+			continue
+		}
+
+		if query.Condition == nil || query.Condition.MatchString(context.Condition) {
+			return true
+		}
+	}
+
+	return false
+}
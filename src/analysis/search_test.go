@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestSearch(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("X"),
+		yuristest.Command("IF", yuristest.Variable(1)),
+		yuristest.Command("X"),
+		yuristest.Command("IFBLEND"),
+		yuristest.Command("ELSE", yuristest.Variable(2)),
+		yuristest.Command("LOOP", yuristest.Token(0x42, 3)),
+		yuristest.Command("X"),
+		yuristest.Command("LOOPEND"),
+		yuristest.Command("IFEND"),
+		yuristest.Command("X"),
+	)
+	labels := []yuris.Label{{Name: "A", Offset: 1}, {Name: "B", Offset: 9}}
+	lines, _ := yuris.Decompile(script, compiler, labels, yuris.Options{})
+
+	tests := []struct {
+		name		string
+		query		Query
+		want		[]string
+	}{
+		{
+			name:	"every label",
+			query:	Query{Command: "X"},
+			want:	[]string{":0", "A:2", "A:6", "B:9"},
+		},
+		{
+			name:	"one label",
+			query:	Query{Command: "X", Label: "A"},
+			want:	[]string{"A:2", "A:6"},
+		},
+		{
+			name:	"label marker",
+			query:	Query{Command: "LABEL", Label: "B"},
+			want:	[]string{"B:9"},
+		},
+		{
+			name:	"inside any IF",
+			query:	Query{Command: "X", Inside: []ContextQuery{{"IF", nil}}},
+			want:	[]string{"A:2", "A:6"},
+		},
+		{
+			name:	"inside an ELSE condition",
+			query:	Query{Command: "X", Inside: []ContextQuery{{"IF", regexp.MustCompile("var2")}}},
+			want:	[]string{"A:6"},
+		},
+		{
+			name:	"inside IF and LOOP",
+			query:	Query{Command: "X", Inside: []ContextQuery{{"IF", nil}, {"LOOP", regexp.MustCompile("^3$")}}},
+			want:	[]string{"A:6"},
+		},
+		{
+			name:	"inside a LOOP of another count",
+			query:	Query{Command: "X", Inside: []ContextQuery{{"LOOP", regexp.MustCompile("^4$")}}},
+			want:	[]string{},
+		},
+		{
+			name:	"branch lines",
+			query:	Query{Attribute: "CONDITION", Value: regexp.MustCompile("var")},
+			want:	[]string{"A:1", "A:4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, match := range Search(lines, 0, test.query) {
+				got = append(got, fmt.Sprintf("%s:%d", match.Label, match.Index))
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("matches = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("IF", yuristest.Variable(1)),
+		yuristest.Command("LOOP", yuristest.Token(0x42, 3)),
+		yuristest.Command("X"),
+		yuristest.Command("LOOPEND"),
+		yuristest.Command("IFEND"),
+	)
+	lines, _ := yuris.Decompile(script, compiler, nil, yuris.Options{})
+
+	matches := Search(lines, 0, Query{Command: "X"})
+	want := []Context{{"IF", "@var1"}, {"LOOP", "3"}}
+	if len(matches) != 1 || !reflect.DeepEqual(matches[0].Context, want) {
+		t.Errorf("matches = %+v, want one with context %+v", matches, want)
+	}
+}
+
+func TestSearchLabelBlock(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("X"),
+		yuristest.Command("RETURN"),
+		yuristest.Command("X"),
+	)
+	labels := []yuris.Label{{Name: "SUB", Offset: 0}}
+	lines, _ := yuris.Decompile(script, compiler, labels, yuris.Options{})
+
+	got := make([]string, 0)
+	for _, match := range Search(lines, 0, Query{Command: "X"}) {
+		got = append(got, fmt.Sprintf("%s:%d", match.Label, match.Index))
+	}
+
+	if want := []string{"SUB:0", ":2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matches = %v, want %v", got, want)
+	}
+}
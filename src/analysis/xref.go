@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type AccessKind string
+
+const (
+	Declare		AccessKind = "declare"
+	Write		AccessKind = "write"
+	Read		AccessKind = "read"
+)
+
+// A single access of a variable by a command.
+type Reference struct {
+	Script		int			`json:"script"`
+	Index		int			`json:"index"`
+	Command		string		`json:"command"`
+	Attribute	string		`json:"attribute"`
+	Kind		AccessKind	`json:"kind"`
+}
+
+type VariableRefs struct {
+	Key			string		`json:"key"`
+	Name		string		`json:"name"`
+	Prefix		string		`json:"prefix"`
+	Id			int16		`json:"id"`
+	References	[]Reference	`json:"references"`
+}
+
+// Cross-references of every variable read or written by the added scripts.
+type Xref struct {
+	Symbols		*yuris.Symbols
+	Variables	map[string]*VariableRefs
+}
+
+func NewXref(symbols *yuris.Symbols) *Xref {
+	return &Xref{symbols, make(map[string]*VariableRefs)}
+}
+
+// Records the variable accesses of every command of a script. The target of
+// a LET is written, and also read by compound assignments. Every other
+// variable in conditions and arguments is read.
+func (x *Xref) AddScript(scriptIndex int, script yuris.Script, compiler yuris.CompilerDefinition) {
+	for i, command := range script.Commands {
+		name := compiler.Commands[command.Id]
+
+		for j, attribute := range script.CommandAttributes(command) {
+			attrName := compiler.Definition(command.Id, attribute.Id).Name
+			if name == "LET" {
+				attrName = fmt.Sprintf("Operand%d", j + 1)
+			}
+
+			isTarget := j == 0 && (name == "LET" || yuris.IsDeclaration(name))
+			for _, token := range yuris.Tokenize(attribute.Bytes) {
+				if !token.IsVariable() {
+					continue
+				}
+
+				ref := Reference{scriptIndex, i, name, attrName, Read}
+				prefix, id := token.Variable()
+
+				if isTarget {
+					// Only the first variable is the target, the rest index
+					// into it.
+					isTarget = false
+
+					ref.Kind = Write
+					if yuris.IsDeclaration(name) {
+						ref.Kind = Declare
+					}
+
+					x.add(prefix, id, ref)
+
+					if name == "LET" && attribute.Type[1] != 0 {
+						ref.Kind = Read
+						x.add(prefix, id, ref)
+					}
+					continue
+				}
+
+				x.add(prefix, id, ref)
+			}
+		}
+	}
+}
+
+func (x *Xref) add(prefix string, id int16, ref Reference) {
+	key := yuris.VariableKey(prefix, id)
+	variable, ok := x.Variables[key]
+	if !ok {
+		variable = &VariableRefs{key, x.Symbols.VariableName(prefix, id), prefix, id, nil}
+		x.Variables[key] = variable
+	}
+
+	variable.References = append(variable.References, ref)
+}
+
+// Returns the variables sorted by prefix and id.
+func (x *Xref) Sorted() []*VariableRefs {
+	variables := make([]*VariableRefs, 0, len(x.Variables))
+	for _, variable := range x.Variables {
+		variables = append(variables, variable)
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].Prefix != variables[j].Prefix {
+			return variables[i].Prefix < variables[j].Prefix
+		}
+		return variables[i].Id < variables[j].Id
+	})
+
+	return variables
+}
+
+// Counts the references of the given kind.
+func (v *VariableRefs) Count(kind AccessKind) int {
+	count := 0
+	for _, ref := range v.References {
+		if ref.Kind == kind {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Renders the cross-references as text, one variable per block.
+func (x *Xref) ToText() string {
+	sb := utils.NewStringBuilder()
+	for _, variable := range x.Sorted() {
+		sb.Append(variable.Name)
+		if variable.Name != variable.Key {
+			sb.Append(fmt.Sprintf(" (%s)", variable.Key))
+		}
+		sb.Append(fmt.Sprintf(": %d writes, %d reads\n", variable.Count(Write) + variable.Count(Declare), variable.Count(Read)))
+
+		for _, ref := range variable.References {
+			sb.Append(fmt.Sprintf("  %-7s yst%05d:%d  %s[%s]\n", ref.Kind, ref.Script, ref.Index, ref.Command, ref.Attribute))
+		}
+	}
+
+	return sb.ToString()
+}
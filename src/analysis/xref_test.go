@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestXrefAssignments(t *testing.T) {
+	element := yuristest.Expr(yuristest.Token(0x56, '@', 3, 0), yuristest.Variable(4), yuristest.Token(0x29))
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("LET", yuristest.Variable(1), yuristest.Variable(2)),
+		yuristest.Command("LET", yuristest.Variable(1), yuristest.Variable(2)),
+		yuristest.Command("LET", element, yuristest.Token(0x42, 1)),
+	)
+
+	// Turn the second and third LET into += and -=.
+	script.Attributes[2].Type[1] = 1
+	script.Attributes[4].Type[1] = 2
+
+	x := NewXref(nil)
+	x.AddScript(0, script, compiler)
+
+	tests := []struct {
+		key			string
+		want		[]Reference
+	}{
+		{
+			key:	"@var1",
+			want:	[]Reference{
+				{0, 0, "LET", "Operand1", Write},
+				{0, 1, "LET", "Operand1", Write},
+				{0, 1, "LET", "Operand1", Read},
+			},
+		},
+		{
+			key:	"@var2",
+			want:	[]Reference{
+				{0, 0, "LET", "Operand2", Read},
+				{0, 1, "LET", "Operand2", Read},
+			},
+		},
+		{
+			key:	"@var3",
+			want:	[]Reference{
+				{0, 2, "LET", "Operand1", Write},
+				{0, 2, "LET", "Operand1", Read},
+			},
+		},
+		{
+			key:	"@var4",
+			want:	[]Reference{{0, 2, "LET", "Operand1", Read}},
+		},
+	}
+
+	for _, test := range tests {
+		variable, ok := x.Variables[test.key]
+		if !ok {
+			t.Errorf("%s has no references", test.key)
+			continue
+		}
+
+		if !reflect.DeepEqual(variable.References, test.want) {
+			t.Errorf("%s references = %+v, want %+v", test.key, variable.References, test.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/damianfadri/yuris-decompiler/analysis"
+)
+
+type assetReport struct {
+	Assets		[]*analysis.Asset		`json:"assets"`
+	Dynamic		[]analysis.DynamicAsset	`json:"dynamic"`
+	Missing		[]*analysis.Asset		`json:"missing,omitempty"`
+	Unused		[]string				`json:"unused,omitempty"`
+}
+
+func runAssets(args []string) error {
+	opts := options{}
+	fs := newFlagSet("assets", "<yst00xxx.ybn|ysbin dir>...", "Lists the asset files referenced by scripts", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	attributeNames := fs.String("attributes", "FILE", "comma separated names of the attributes with asset paths")
+	commandNames := fs.String("commands", "", "comma separated command names to scan, all if empty")
+	dir := fs.String("dir", "", "asset directory or YPF archive to check for missing and unused files")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	scanner := analysis.NewAssetScanner(splitNames(*attributeNames), splitNames(*commandNames))
+	for _, input := range inputs {
+		id, err := opts.scriptIdOf(input)
+		if err != nil {
+			return err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		scanner.AddScript(id, script, compiler)
+	}
+
+	report := assetReport{Assets: scanner.Sorted(), Dynamic: scanner.Dynamic}
+	if *dir != "" {
+		report.Missing, report.Unused, err = scanner.Check(*dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *dir, err)
+		}
+
+		if len(report.Missing) > 0 {
+			opts.warn("%d referenced assets are missing from %s", len(report.Missing), *dir)
+		}
+	}
+
+	for _, dynamic := range report.Dynamic {
+		ref := dynamic.Reference
+		opts.logf("yst%05d:%d: cannot check computed asset path %s", ref.Script, ref.Index, dynamic.Expression)
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, report)
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "ASSET\tCOMMAND\tLOCATION")
+		for _, asset := range report.Assets {
+			for _, ref := range asset.References {
+				fmt.Fprintf(tw, "%s\t%s[%s]\tyst%05d:%d\n", asset.Path, ref.Command, ref.Attribute, ref.Script, ref.Index)
+			}
+		}
+		for _, dynamic := range report.Dynamic {
+			ref := dynamic.Reference
+			fmt.Fprintf(tw, "%s\t%s[%s]\tyst%05d:%d\n", dynamic.Expression, ref.Command, ref.Attribute, ref.Script, ref.Index)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		if *dir == "" {
+			return nil
+		}
+
+		fmt.Fprintf(w, "\nMissing: %d\n", len(report.Missing))
+		for _, asset := range report.Missing {
+			fmt.Fprintf(w, "  %s\n", asset.Path)
+		}
+
+		fmt.Fprintf(w, "\nUnused: %d\n", len(report.Unused))
+		for _, file := range report.Unused {
+			fmt.Fprintf(w, "  %s\n", file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+// Splits a comma separated flag value, skipping empty names.
+func splitNames(value string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(value, ",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
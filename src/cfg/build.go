@@ -0,0 +1,280 @@
+package cfg
+
+import (
+	"sort"
+
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// Structural information about the command stream, used to resolve the
+// targets of the control flow commands. It is returned by Analyze for
+// callers that follow the commands themselves, such as the interpreter,
+// rather than the blocks of a Graph.
+type Structure struct {
+	Names		[]string
+	BranchNext	map[int]int		// IF or ELSE to the next ELSE or IFEND
+	BranchEnd	map[int]int		// IF, ELSE or IFBLEND to its IFEND
+	ElseHeads	map[int]bool
+	LoopHead	map[int]int		// LOOPEND, LOOPBREAK or LOOPCONTINUE to its LOOP
+	LoopEnd		map[int]int		// LOOP to its LOOPEND
+}
+
+type openIf struct {
+	heads		*dsa.List[int]
+	blends		*dsa.List[int]
+}
+
+// Builds the control flow graph of a whole script. The labels must belong
+// to the given script, as returned by yuris.ScriptLabels.
+func Build(script yuris.Script, compiler yuris.CompilerDefinition, labels []yuris.Label) *Graph {
+	s := Analyze(script, compiler)
+	count := len(s.Names)
+
+	labelsAt := make(map[int][]string)
+	labelOffsets := make(map[string]int)
+	for _, label := range labels {
+		labelsAt[label.Offset] = append(labelsAt[label.Offset], label.Name)
+		labelOffsets[label.Name] = label.Offset
+	}
+
+	// Find the first command of every block.
+	leaders := make(map[int]bool)
+	leaders[0] = true
+	for offset := range labelsAt {
+		if offset < count {
+			leaders[offset] = true
+		}
+	}
+
+	for i, name := range s.Names {
+		switch name {
+		case "IF", "ELSE", "IFEND", "LOOP", "LOOPEND":
+			leaders[i] = true
+		}
+
+		switch name {
+		case "IF", "ELSE", "IFBLEND", "LOOP", "LOOPEND", "LOOPBREAK",
+			"LOOPCONTINUE", "GOTO", "RETURN", "END":
+			leaders[i + 1] = true
+		}
+	}
+
+	starts := dsa.NewList[int]()
+	for leader := range leaders {
+		if leader < count {
+			starts.Add(leader)
+		}
+	}
+	sort.Ints(starts.Items)
+
+	g := &Graph{Name: "main"}
+	blockAt := make(map[int]*Block)
+	for i, start := range starts.Items {
+		end := count
+		if i + 1 < starts.Count() {
+			end = starts.Items[i + 1]
+		}
+
+		block := &Block{
+			Id:			i,
+			Start:		start,
+			End:		end,
+			Labels:		labelsAt[start],
+			Commands:	s.Names[start:end],
+		}
+
+		g.Blocks = append(g.Blocks, block)
+		blockAt[start] = block
+	}
+
+	if len(g.Blocks) > 0 {
+		g.Entry = g.Blocks[0]
+	}
+
+	// Natural fall-through into an ELSE means the previous branch was taken,
+	// so control continues after the IFEND instead.
+	fallthroughTo := func(from *Block, index int) {
+		if s.ElseHeads[index] {
+			if end, ok := s.BranchEnd[index]; ok {
+				if to, ok := blockAt[end]; ok {
+					addEdge(from, to, Jump)
+				}
+			}
+			return
+		}
+
+		if to, ok := blockAt[index]; ok {
+			addEdge(from, to, Fallthrough)
+		}
+	}
+
+	edgeTo := func(from *Block, index int, kind EdgeKind) {
+		if to, ok := blockAt[index]; ok {
+			addEdge(from, to, kind)
+		}
+	}
+
+	for _, block := range g.Blocks {
+		last := block.End - 1
+		command := script.Commands[last]
+
+		switch s.Names[last] {
+		case "IF", "ELSE":
+			fallthroughTo(block, last + 1)
+			if next, ok := s.BranchNext[last]; ok && command.NumAttributes > 0 {
+				edgeTo(block, next, Branch)
+			}
+		case "LOOP":
+			fallthroughTo(block, last + 1)
+			if end, ok := s.LoopEnd[last]; ok && mayRunZeroTimes(script, command) {
+				edgeTo(block, end + 1, Branch)
+			}
+		case "IFBLEND":
+			if end, ok := s.BranchEnd[last]; ok {
+				edgeTo(block, end, Jump)
+			}
+		case "LOOPEND":
+			if head, ok := s.LoopHead[last]; ok {
+				edgeTo(block, head + 1, Back)
+			}
+			fallthroughTo(block, last + 1)
+		case "LOOPBREAK":
+			if head, ok := s.LoopHead[last]; ok {
+				if end, ok := s.LoopEnd[head]; ok {
+					edgeTo(block, end + 1, Break)
+				}
+			}
+		case "LOOPCONTINUE":
+			if head, ok := s.LoopHead[last]; ok {
+				if end, ok := s.LoopEnd[head]; ok {
+					edgeTo(block, end, Continue)
+				}
+			}
+		case "GOTO":
+			target := targetLabel(script, command)
+			if offset, ok := labelOffsets[target]; ok && offset < count {
+				edgeTo(block, offset, Jump)
+			} else if target != "" {
+				block.Exits = append(block.Exits, target)
+			}
+		case "RETURN", "END":
+		default:
+			fallthroughTo(block, last + 1)
+		}
+
+		for i := block.Start; i < block.End; i++ {
+			if s.Names[i] == "GOSUB" {
+				if target := targetLabel(script, script.Commands[i]); target != "" {
+					block.Calls = append(block.Calls, target)
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// Builds the control flow graph of a script and splits it into one graph
+// per label, keyed by label name.
+func BuildLabels(script yuris.Script, compiler yuris.CompilerDefinition, labels []yuris.Label) map[string]*Graph {
+	g := Build(script, compiler, labels)
+
+	graphs := make(map[string]*Graph)
+	for _, label := range labels {
+		if sub := g.ForLabel(label.Name); sub != nil {
+			graphs[label.Name] = sub
+		}
+	}
+
+	return graphs
+}
+
+// Matches the IF, ELSE and LOOP commands of a script with their ends.
+func Analyze(script yuris.Script, compiler yuris.CompilerDefinition) Structure {
+	s := Structure{
+		Names:		make([]string, len(script.Commands)),
+		BranchNext:	make(map[int]int),
+		BranchEnd:	make(map[int]int),
+		ElseHeads:	make(map[int]bool),
+		LoopHead:	make(map[int]int),
+		LoopEnd:	make(map[int]int),
+	}
+
+	ifs := dsa.NewStack[openIf]()
+	loops := dsa.NewStack[int]()
+
+	for i, command := range script.Commands {
+		name := compiler.Commands[command.Id]
+		s.Names[i] = name
+
+		switch name {
+		case "IF":
+			heads := dsa.NewList[int]()
+			heads.Add(i)
+			ifs.Push(openIf{heads, dsa.NewList[int]()})
+		case "ELSE":
+			if ifs.Count() == 0 {
+				break
+			}
+			curr := ifs.Peek()
+			s.BranchNext[curr.heads.Items[curr.heads.Count() - 1]] = i
+			s.ElseHeads[i] = true
+			curr.heads.Add(i)
+		case "IFBLEND":
+			if ifs.Count() == 0 {
+				break
+			}
+			curr := ifs.Peek()
+			curr.blends.Add(i)
+		case "IFEND":
+			if ifs.Count() == 0 {
+				break
+			}
+			curr := ifs.Pop()
+			s.BranchNext[curr.heads.Items[curr.heads.Count() - 1]] = i
+			for _, head := range curr.heads.Items {
+				s.BranchEnd[head] = i
+			}
+			for _, blend := range curr.blends.Items {
+				s.BranchEnd[blend] = i
+			}
+		case "LOOP":
+			loops.Push(i)
+		case "LOOPBREAK", "LOOPCONTINUE":
+			if loops.Count() > 0 {
+				s.LoopHead[i] = loops.Peek()
+			}
+		case "LOOPEND":
+			if loops.Count() == 0 {
+				break
+			}
+			head := loops.Pop()
+			s.LoopHead[i] = head
+			s.LoopEnd[head] = i
+		}
+	}
+
+	return s
+}
+
+// Returns true if the LOOP count is zero or only known at run time. A LOOP
+// without a count repeats until LOOPBREAK.
+func mayRunZeroTimes(script yuris.Script, command yuris.Command) bool {
+	attributes := script.CommandAttributes(command)
+	if len(attributes) == 0 {
+		return false
+	}
+
+	value, err := attributes[0].Evaluate(nil)
+	return err != nil || value.ToNumber().Int == 0
+}
+
+func targetLabel(script yuris.Script, command yuris.Command) string {
+	attributes := script.CommandAttributes(command)
+	if len(attributes) == 0 {
+		return ""
+	}
+
+	return yuris.LabelName(attributes[0].Decompile())
+}
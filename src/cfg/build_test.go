@@ -0,0 +1,214 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+var (
+	count0		= yuristest.Token(0x42, 0)
+	count3		= yuristest.Token(0x42, 3)
+	endless		= yuristest.Token(0x42, 0xff)
+	variable	= yuristest.Variable(1)
+)
+
+// Lists the edges of a graph by the start of their blocks.
+func edges(g *Graph) []string {
+	result := make([]string, 0)
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			result = append(result, fmt.Sprintf("%d->%d %s", edge.From.Start, edge.To.Start, edge.Kind))
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+func TestBuildEdges(t *testing.T) {
+	tests := []struct {
+		name		string
+		commands	[]yuristest.CommandSpec
+		labels		[]yuris.Label
+		edges		[]string
+	}{
+		{
+			name: "if else",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF", variable),
+				yuristest.Command("_"),
+				yuristest.Command("IFBLEND"),
+				yuristest.Command("ELSE"),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+				yuristest.Command("END"),
+			},
+			edges: []string{"0->1 fallthrough", "0->3 branch", "1->5 jump", "3->4 fallthrough", "4->5 fallthrough"},
+		},
+		{
+			name: "else if falls through to ifend",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF", variable),
+				yuristest.Command("_"),
+				yuristest.Command("ELSE", variable),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+				yuristest.Command("END"),
+			},
+			edges: []string{"0->1 fallthrough", "0->2 branch", "1->4 jump", "2->3 fallthrough", "2->4 branch", "3->4 fallthrough"},
+		},
+		{
+			name: "loop with constant count",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("LOOP", count3),
+				yuristest.Command("_"),
+				yuristest.Command("LOOPEND"),
+				yuristest.Command("END"),
+			},
+			edges: []string{"0->1 fallthrough", "1->2 fallthrough", "2->1 back", "2->3 fallthrough"},
+		},
+		{
+			name: "loop with zero count",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("LOOP", count0),
+				yuristest.Command("_"),
+				yuristest.Command("LOOPEND"),
+				yuristest.Command("END"),
+			},
+			edges: []string{"0->1 fallthrough", "0->3 branch", "1->2 fallthrough", "2->1 back", "2->3 fallthrough"},
+		},
+		{
+			name: "loop with variable count",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("LOOP", variable),
+				yuristest.Command("_"),
+				yuristest.Command("LOOPEND"),
+				yuristest.Command("END"),
+			},
+			edges: []string{"0->1 fallthrough", "0->3 branch", "1->2 fallthrough", "2->1 back", "2->3 fallthrough"},
+		},
+		{
+			name: "endless loop",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("LOOP", endless),
+				yuristest.Command("LOOPBREAK"),
+				yuristest.Command("LOOPCONTINUE"),
+				yuristest.Command("LOOPEND"),
+				yuristest.Command("END"),
+			},
+			edges: []string{"0->1 fallthrough", "1->4 break", "2->3 continue", "3->1 back", "3->4 fallthrough"},
+		},
+		{
+			name: "goto",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("GOTO", yuristest.LabelRef("A")),
+				yuristest.Command("_"),
+				yuristest.Command("END"),
+			},
+			labels: []yuris.Label{{Name: "A", Offset: 2}},
+			edges: []string{"0->2 jump", "1->2 fallthrough"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, compiler := yuristest.NewScript(test.commands...)
+			g := Build(script, compiler, test.labels)
+
+			if got := edges(g); !reflect.DeepEqual(got, test.edges) {
+				t.Errorf("edges = %v, want %v", got, test.edges)
+			}
+		})
+	}
+}
+
+func TestBuildExitsAndCalls(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("GOSUB", yuristest.LabelRef("SUB")),
+		yuristest.Command("GOTO", yuristest.LabelRef("ELSEWHERE")),
+		yuristest.Command("_"),
+		yuristest.Command("RETURN"),
+	)
+	g := Build(script, compiler, []yuris.Label{{Name: "SUB", Offset: 2}})
+
+	if got := g.Entry.Calls; !reflect.DeepEqual(got, []string{"SUB"}) {
+		t.Errorf("calls = %v, want [SUB]", got)
+	}
+	if got := g.Entry.Exits; !reflect.DeepEqual(got, []string{"ELSEWHERE"}) {
+		t.Errorf("exits = %v, want [ELSEWHERE]", got)
+	}
+	if got := g.Unreachable(); len(got) != 0 {
+		t.Errorf("unreachable = %v, want none since SUB is called", got)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("LOOP", count3),
+		yuristest.Command("LOOP", count3),
+		yuristest.Command("_"),
+		yuristest.Command("LOOPEND"),
+		yuristest.Command("LOOPEND"),
+		yuristest.Command("END"),
+	)
+	loops := Build(script, compiler, nil).Loops()
+
+	if len(loops) != 2 {
+		t.Fatalf("found %d loops, want 2", len(loops))
+	}
+
+	outer, inner := loops[0], loops[1]
+	if outer.Head.Start != 1 || inner.Head.Start != 2 {
+		t.Errorf("loop heads start at %d and %d, want 1 and 2", outer.Head.Start, inner.Head.Start)
+	}
+	if inner.Parent != outer || outer.Parent != nil {
+		t.Errorf("inner loop is not nested in the outer loop")
+	}
+	if !contains(outer.Blocks, inner.Head) {
+		t.Errorf("outer loop does not contain the inner loop")
+	}
+}
+
+func TestBuildLabels(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("GOSUB", yuristest.LabelRef("SUB")),
+		yuristest.Command("END"),
+		yuristest.Command("_"),
+		yuristest.Command("RETURN"),
+	)
+	labels := []yuris.Label{{Name: "MAIN", Offset: 0}, {Name: "SUB", Offset: 2}}
+	graphs := BuildLabels(script, compiler, labels)
+
+	sub := graphs["SUB"]
+	if sub == nil || len(sub.Blocks) != 1 || sub.Entry.Start != 2 {
+		t.Fatalf("SUB graph = %+v, want a single block at 2", sub)
+	}
+
+	if main := graphs["MAIN"]; main == nil || len(main.Blocks) != 1 {
+		t.Errorf("MAIN graph should only contain its own block")
+	}
+}
+
+func TestToDot(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("GOTO", yuristest.LabelRef("A\"B")),
+		yuristest.Command("END"),
+	)
+	dot := Build(script, compiler, nil).ToDot()
+
+	for _, want := range []string{
+		"digraph \"main\" {",
+		"B0 -> \"#A\\\"B\" [style=dashed label=\"goto\"];",
+		"B1 [label=\"B1 [1, 2)\\lEND\\l\"];",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output does not contain %q:\n%s", want, dot)
+		}
+	}
+}
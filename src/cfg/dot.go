@@ -0,0 +1,43 @@
+package cfg
+
+import (
+	"fmt"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+)
+
+// Renders the graph in Graphviz DOT format.
+func (g *Graph) ToDot() string {
+	sb := utils.NewStringBuilder()
+
+	sb.Append(fmt.Sprintf("digraph %s {\n", utils.QuoteDot(g.Name)))
+	sb.Append("  node [shape=box fontname=monospace];\n")
+
+	for _, block := range g.Blocks {
+		text := fmt.Sprintf("B%d [%d, %d)\\l", block.Id, block.Start, block.End)
+		for _, label := range block.Labels {
+			text += utils.EscapeDot("#=" + label) + "\\l"
+		}
+		for _, command := range block.Commands {
+			text += utils.EscapeDot(command) + "\\l"
+		}
+
+		sb.Append(fmt.Sprintf("  B%d [label=\"%s\"];\n", block.Id, text))
+
+		for _, exit := range block.Exits {
+			sb.Append(fmt.Sprintf("  B%d -> %s [style=dashed label=\"goto\"];\n", block.Id, utils.QuoteDot("#" + exit)))
+		}
+		for _, call := range block.Calls {
+			sb.Append(fmt.Sprintf("  B%d -> %s [style=dotted label=\"gosub\"];\n", block.Id, utils.QuoteDot("#" + call)))
+		}
+	}
+
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			sb.Append(fmt.Sprintf("  B%d -> B%d [label=\"%s\"];\n", edge.From.Id, edge.To.Id, edge.Kind))
+		}
+	}
+
+	sb.Append("}\n")
+	return sb.ToString()
+}
@@ -0,0 +1,317 @@
+package cfg
+
+import (
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+)
+
+type EdgeKind int
+
+const (
+	Fallthrough EdgeKind = iota
+	Branch				// condition of an IF or ELSE failed, or a LOOP ran zero times
+	Jump				// GOTO, or the end of an IF branch to its IFEND
+	Back				// LOOPEND to the loop body
+	Break				// LOOPBREAK to the command after LOOPEND
+	Continue			// LOOPCONTINUE to LOOPEND
+)
+
+func (kind EdgeKind) String() string {
+	switch kind {
+	case Fallthrough:
+		return "fallthrough"
+	case Branch:
+		return "branch"
+	case Jump:
+		return "jump"
+	case Back:
+		return "back"
+	case Break:
+		return "break"
+	case Continue:
+		return "continue"
+	}
+
+	return "unknown"
+}
+
+type Edge struct {
+	From		*Block
+	To			*Block
+	Kind		EdgeKind
+}
+
+// A basic block covering the commands in [Start, End).
+type Block struct {
+	Id			int
+	Start		int
+	End			int
+	Labels		[]string
+	Commands	[]string
+	Succs		[]Edge
+	Preds		[]Edge
+
+	// Labels outside of the script targeted by GOTO, and labels called by
+	// GOSUB from this block.
+	Exits		[]string
+	Calls		[]string
+}
+
+type Graph struct {
+	Name		string
+	Entry		*Block
+	Blocks		[]*Block
+}
+
+type Loop struct {
+	Head		*Block
+	Blocks		[]*Block
+	Parent		*Loop
+}
+
+// Returns the last command of the block, or an empty string if the block
+// is empty.
+func (b *Block) Last() string {
+	if len(b.Commands) == 0 {
+		return ""
+	}
+
+	return b.Commands[len(b.Commands) - 1]
+}
+
+// Returns the block containing the command at the given index.
+func (g *Graph) BlockAt(index int) *Block {
+	for _, block := range g.Blocks {
+		if block.Start <= index && index < block.End {
+			return block
+		}
+	}
+
+	return nil
+}
+
+// Returns the block starting at the given label.
+func (g *Graph) BlockOf(label string) *Block {
+	for _, block := range g.Blocks {
+		for _, name := range block.Labels {
+			if name == label {
+				return block
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the set of blocks reachable from the entry block, including
+// subroutines of this graph called through GOSUB.
+func (g *Graph) Reachable() map[*Block]bool {
+	if g.Entry == nil {
+		return make(map[*Block]bool)
+	}
+
+	return g.ReachableFrom([]*Block{g.Entry})
+}
+
+// Returns the set of blocks reachable from the entry block or from any
+// label, since labels can be targeted from other scripts.
+func (g *Graph) ReachableFromLabels() map[*Block]bool {
+	roots := dsa.NewList[*Block]()
+	for _, block := range g.Blocks {
+		if block == g.Entry || len(block.Labels) > 0 {
+			roots.Add(block)
+		}
+	}
+
+	return g.ReachableFrom(roots.Items)
+}
+
+// Returns the set of blocks reachable from the given blocks, including
+// subroutines of this graph called through GOSUB.
+func (g *Graph) ReachableFrom(roots []*Block) map[*Block]bool {
+	visited := make(map[*Block]bool)
+	stack := dsa.NewStack[*Block]()
+	for _, root := range roots {
+		if !visited[root] {
+			visited[root] = true
+			stack.Push(root)
+		}
+	}
+
+	for stack.Count() > 0 {
+		curr := stack.Pop()
+
+		next := dsa.NewList[*Block]()
+		for _, edge := range curr.Succs {
+			next.Add(edge.To)
+		}
+		for _, call := range curr.Calls {
+			if block := g.BlockOf(call); block != nil {
+				next.Add(block)
+			}
+		}
+
+		for _, block := range next.Items {
+			if !visited[block] {
+				visited[block] = true
+				stack.Push(block)
+			}
+		}
+	}
+
+	return visited
+}
+
+// Returns the blocks that cannot be reached from the entry block.
+func (g *Graph) Unreachable() []*Block {
+	reachable := g.Reachable()
+	blocks := dsa.NewList[*Block]()
+	for _, block := range g.Blocks {
+		if !reachable[block] {
+			blocks.Add(block)
+		}
+	}
+
+	return blocks.Items
+}
+
+// Returns the natural loops of the graph, outermost loops first. Each back
+// edge contributes its blocks to the loop of its target.
+func (g *Graph) Loops() []*Loop {
+	loops := dsa.NewList[*Loop]()
+	byHead := make(map[*Block]*Loop)
+
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			if edge.Kind != Back {
+				continue
+			}
+
+			loop, ok := byHead[edge.To]
+			if !ok {
+				loop = &Loop{Head: edge.To, Blocks: []*Block{edge.To}}
+				byHead[edge.To] = loop
+				loops.Add(loop)
+			}
+
+			members := make(map[*Block]bool)
+			for _, member := range loop.Blocks {
+				members[member] = true
+			}
+
+			// Walk predecessors from the tail until reaching the head.
+			stack := dsa.NewStack[*Block]()
+			if !members[edge.From] {
+				members[edge.From] = true
+				loop.Blocks = append(loop.Blocks, edge.From)
+				stack.Push(edge.From)
+			}
+
+			for stack.Count() > 0 {
+				curr := stack.Pop()
+				for _, pred := range curr.Preds {
+					if !members[pred.From] {
+						members[pred.From] = true
+						loop.Blocks = append(loop.Blocks, pred.From)
+						stack.Push(pred.From)
+					}
+				}
+			}
+		}
+	}
+
+	loops.Sort(func(i, j int) bool {
+		return len(loops.Items[i].Blocks) > len(loops.Items[j].Blocks)
+	})
+
+	// The parent of a loop is the smallest loop containing its head.
+	for i, loop := range loops.Items {
+		for j := i - 1; j >= 0; j-- {
+			if contains(loops.Items[j].Blocks, loop.Head) {
+				loop.Parent = loops.Items[j]
+				break
+			}
+		}
+	}
+
+	return loops.Items
+}
+
+// Returns the subgraph of blocks reachable from the given label, or nil if
+// the label does not start a block in this graph.
+func (g *Graph) ForLabel(label string) *Graph {
+	entry := g.BlockOf(label)
+	if entry == nil {
+		return nil
+	}
+
+	reachable := reachableFrom(entry)
+
+	sub := &Graph{Name: label}
+	copies := make(map[*Block]*Block)
+	for _, block := range g.Blocks {
+		if !reachable[block] {
+			continue
+		}
+
+		copied := &Block{
+			Id:			block.Id,
+			Start:		block.Start,
+			End:		block.End,
+			Labels:		block.Labels,
+			Commands:	block.Commands,
+			Exits:		block.Exits,
+			Calls:		block.Calls,
+		}
+		copies[block] = copied
+		sub.Blocks = append(sub.Blocks, copied)
+	}
+
+	for _, block := range g.Blocks {
+		from, ok := copies[block]
+		if !ok {
+			continue
+		}
+
+		for _, edge := range block.Succs {
+			addEdge(from, copies[edge.To], edge.Kind)
+		}
+	}
+
+	sub.Entry = copies[entry]
+	return sub
+}
+
+func reachableFrom(entry *Block) map[*Block]bool {
+	visited := make(map[*Block]bool)
+	stack := dsa.NewStack[*Block]()
+	stack.Push(entry)
+	visited[entry] = true
+
+	for stack.Count() > 0 {
+		curr := stack.Pop()
+		for _, edge := range curr.Succs {
+			if !visited[edge.To] {
+				visited[edge.To] = true
+				stack.Push(edge.To)
+			}
+		}
+	}
+
+	return visited
+}
+
+func addEdge(from *Block, to *Block, kind EdgeKind) {
+	edge := Edge{from, to, kind}
+	from.Succs = append(from.Succs, edge)
+	to.Preds = append(to.Preds, edge)
+}
+
+func contains(blocks []*Block, block *Block) bool {
+	for _, b := range blocks {
+		if b == block {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/cfg"
+)
+
+func runCfg(args []string) error {
+	opts := options{}
+	fs := newFlagSet("cfg", "<yst00xxx.ybn>", "Writes the control flow graph of a script in DOT format", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	labelName := fs.String("label", "", "only write the blocks reachable from this label")
+	loops := fs.Bool("loops", false, "list the loops of the graph instead of writing it")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 1 {
+		return usageError("expected a single yst00xxx.ybn path")
+	}
+
+	if opts.format != "text" {
+		return usageError("cfg only supports text output")
+	}
+
+	file, err := opts.loadScript(opts.args[0])
+	if err != nil {
+		return err
+	}
+
+	compiler, err := opts.readCompiler(file.Path)
+	if err != nil {
+		return err
+	}
+
+	var graph *cfg.Graph
+	if *labelName != "" {
+		graph = cfg.BuildLabels(file.Script, compiler, file.Labels)[*labelName]
+		if graph == nil {
+			return usageError("label %s not found in %s", *labelName, file.Path)
+		}
+	} else {
+		graph = cfg.Build(file.Script, compiler, file.Labels)
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if !*loops {
+			_, err := io.WriteString(w, graph.ToDot())
+			return err
+		}
+
+		for _, loop := range graph.Loops() {
+			fmt.Fprintln(w, formatLoop(loop))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+// Describes a loop as its head, its blocks and its parent loop.
+func formatLoop(loop *cfg.Loop) string {
+	blocks := make([]string, len(loop.Blocks))
+	for i, block := range loop.Blocks {
+		blocks[i] = fmt.Sprintf("B%d", block.Id)
+	}
+
+	line := fmt.Sprintf("loop B%d [%d, %d): %s", loop.Head.Id, loop.Head.Start, loop.Head.End, strings.Join(blocks, " "))
+	if loop.Parent != nil {
+		line += fmt.Sprintf(" (inside loop B%d)", loop.Parent.Head.Id)
+	}
+
+	return line
+}
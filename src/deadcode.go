@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/damianfadri/yuris-decompiler/analysis"
+)
+
+func runDeadCode(args []string) error {
+	opts := options{}
+	fs := newFlagSet("deadcode", "<ysbin dir>", "Lists unused labels, unreachable code and constant conditions", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 1 {
+		return usageError("expected a single ysbin directory")
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("no scripts found in %s", opts.args[0])
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	labels, err := readLabels(opts.args[0])
+	if err != nil {
+		return err
+	}
+
+	finder := analysis.NewDeadCodeFinder(labels)
+	for _, input := range inputs {
+		id, err := scriptId(input)
+		if err != nil {
+			return err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		finder.AddScript(id, script, compiler)
+	}
+
+	findings := finder.Findings()
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, findings)
+		}
+
+		for _, finding := range findings {
+			fmt.Fprintln(w, finding)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"path/filepath"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runDecompile(args []string) error {
+	opts := options{}
+	fs := newFlagSet("decompile", "<yst00xxx.ybn|ysbin dir|->...", "Decompiles scripts into YU-RIS source", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file when decompiling a single script, - for stdout")
+	fs.StringVar(&opts.outDir, "out-dir", "", "output directory, with one .yst file per script")
+	omitDefaults := fs.Bool("omit-defaults", false, "omit arguments equal to their default in YSCom.ycd, whose meaning is inferred: check that the output recompiles to the same script")
+	labelIds := fs.Bool("label-ids", false, "print the ysl.ybn id of each label as a comment")
+	sourceComments := fs.Bool("source-comments", false, "comment each line with the index, id and attribute descriptors of its command, and each label with its id")
+	symbolsPath := fs.String("symbols", "", "symbol file with variable and label names")
+	fold := fs.Bool("fold", false, "replace constant sub-expressions such as 2 * 3 by their value")
+	indent := fs.String("indent", "2", "indentation of a nesting level: a number of spaces or tab")
+	braces := fs.String("braces", "line", "brace style: line, same or none")
+	spacedEquals := fs.Bool("spaced-equals", false, "put spaces around = between attribute names and values")
+	wrap := fs.Int("wrap", 0, "put each argument on its own line if a line is longer than this, 0 to never wrap")
+	crlf := fs.Bool("crlf", false, "end lines with CRLF")
+	label := fs.String("label", "", "only decompile from this label up to the next label")
+	commandRange := fs.String("range", "", "only decompile the commands in START:END, END excluded")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	if opts.output != "" && opts.outDir != "" {
+		return usageError("-o and -out-dir cannot be used together")
+	}
+
+	if opts.output != "" && len(inputs) > 1 {
+		return usageError("-o can only be used with a single script, use -out-dir")
+	}
+
+	if opts.output == "" && opts.outDir == "" {
+		return usageError("missing output path, use -o or -out-dir")
+	}
+
+	isRegion := *label != "" || *commandRange != ""
+	if isRegion && len(inputs) > 1 {
+		return usageError("-label and -range can only be used with a single script")
+	}
+
+	if *label != "" && *commandRange != "" {
+		return usageError("-label and -range cannot be used together")
+	}
+
+	if _, err := opts.scriptIdOf(inputs[0]); err != nil {
+		return err
+	}
+
+	if opts.outDir != "" {
+		if err := os.MkdirAll(opts.outDir, 0755); err != nil {
+			return outputError(err)
+		}
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	format, err := parseFormat(*indent, *braces, *spacedEquals, *wrap, *crlf)
+	if err != nil {
+		return err
+	}
+
+	decompileOptions := yuris.Options{}
+	decompileOptions.OmitDefaults = *omitDefaults
+	decompileOptions.LabelIds = *labelIds
+	decompileOptions.FoldConstants = *fold
+	decompileOptions.SourceComments = *sourceComments
+
+	if *symbolsPath != "" {
+		decompileOptions.Symbols, err = yuris.ReadSymbols(*symbolsPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *symbolsPath, err)
+		}
+	}
+
+	for _, input := range inputs {
+		file, err := opts.loadScript(input)
+		if err != nil {
+			return err
+		}
+
+		var lines []yuris.Line
+		var warnings []yuris.Warning
+		switch {
+		case *label != "":
+			ysbinPath := opts.ysbinOf(input)
+			if ysbinPath == "" {
+				return usageError("-label needs the labels of ysl.ybn, use -ysbin")
+			}
+
+			labels, err := readLabels(ysbinPath)
+			if err != nil {
+				return err
+			}
+
+			lines, warnings, err = yuris.DecompileLabel(file.Script, compiler, labels, decompileOptions, file.Id, *label)
+			if err != nil {
+				return usageError("%s: %v", input, err)
+			}
+		case *commandRange != "":
+			start, end, err := parseRange(*commandRange, len(file.Script.Commands))
+			if err != nil {
+				return err
+			}
+			lines, warnings = yuris.DecompileRange(file.Script, compiler, file.Labels, decompileOptions, start, end)
+		default:
+			lines, warnings = yuris.Decompile(file.Script, compiler, file.Labels, decompileOptions)
+		}
+
+		for _, warning := range warnings {
+			opts.warn("%s: %s", input, warning)
+		}
+
+		outputPath := opts.output
+		if outputPath == "" {
+			if input == "-" {
+				return usageError("-out-dir cannot be used when reading from stdin, use -o")
+			}
+
+			name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+			outputPath = filepath.Join(opts.outDir, name + ".yst")
+		}
+
+		opts.logf("writing %s", outputPath)
+		err = writeOutput(outputPath, func(w io.Writer) error {
+			return opts.writeLines(w, lines, format)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return opts.finish()
+}
+
+func (opts *options) writeLines(w io.Writer, lines []yuris.Line, format yuris.Format) error {
+	if opts.format == "json" {
+		return writeJSON(w, lines)
+	}
+
+	_, err := io.WriteString(w, format.Lines(lines))
+	return err
+}
+
+// Parses a command range given as START:END. Either bound can be omitted.
+func parseRange(value string, count int) (int, int, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, usageError("invalid -range %q, expected START:END", value)
+	}
+
+	start, end := 0, count
+	var err error
+	if parts[0] != "" {
+		if start, err = strconv.Atoi(parts[0]); err != nil || start < 0 {
+			return 0, 0, usageError("invalid -range start %q", parts[0])
+		}
+	}
+	if parts[1] != "" {
+		if end, err = strconv.Atoi(parts[1]); err != nil || end < start {
+			return 0, 0, usageError("invalid -range end %q", parts[1])
+		}
+	}
+
+	if start > count {
+		return 0, 0, usageError("-range start %d is past the last command %d", start, count - 1)
+	}
+	if end > count {
+		return 0, 0, usageError("-range end %d is past the last command %d, END is excluded", end, count - 1)
+	}
+
+	return start, end, nil
+}
+
+func parseFormat(indent string, braces string, spacedEquals bool, wrap int, crlf bool) (yuris.Format, error) {
+	format := yuris.DefaultFormat()
+	format.SpacedEquals = spacedEquals
+	format.MaxWidth = wrap
+
+	if indent == "tab" {
+		format.Indent = "\t"
+	} else {
+		width, err := strconv.Atoi(indent)
+		if err != nil || width < 0 {
+			return format, usageError("invalid -indent %q, expected a number or tab", indent)
+		}
+		format.Indent = strings.Repeat(" ", width)
+	}
+
+	switch braces {
+	case "line":
+		format.Braces = yuris.BracesOwnLine
+	case "same":
+		format.Braces = yuris.BracesSameLine
+	case "none":
+		format.Braces = yuris.BracesNone
+	default:
+		return format, usageError("invalid -braces %q, expected line, same or none", braces)
+	}
+
+	if crlf {
+		format.Newline = "\r\n"
+	}
+
+	return format, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/analysis"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runDiff(args []string) error {
+	opts := options{}
+	fs := newFlagSet("diff", "<old ysbin dir|ypf> <new ysbin dir|ypf>", "Compares two versions of a game label by label.\nLabels are matched by name. The code before the first label of a script is\nmatched by script index only, yst_list.ybn is not used to follow renamed or\nreordered scripts", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	textNames := fs.String("text", "_", "comma separated names of the text commands")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 2 {
+		return usageError("expected two ysbin directories or YPF archives")
+	}
+
+	versions := make([][]analysis.Segment, 2)
+	for i, dir := range opts.args {
+		segments, err := opts.decompileSegments(dir)
+		if err != nil {
+			return err
+		}
+		versions[i] = segments
+	}
+
+	diffs := analysis.DiffSegments(versions[0], versions[1], splitNames(*textNames))
+	err := opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, diffs)
+		}
+
+		for _, diff := range diffs {
+			writeLabelDiff(w, diff)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+// Decompiles every script of a ysbin directory or a YPF archive into label
+// segments. Labels keep their name across versions, but the segments before
+// the first label are named after the script index, so they only match if
+// the script keeps its index.
+func (opts *options) decompileSegments(dir string) ([]analysis.Segment, error) {
+	var files []scriptFile
+	var compiler yuris.CompilerDefinition
+	var err error
+	if strings.EqualFold(filepath.Ext(dir), ".ypf") {
+		files, compiler, err = opts.loadArchive(dir)
+	} else {
+		files, compiler, err = opts.loadDirectory(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]analysis.Segment, 0)
+	for _, file := range files {
+		lines, warnings := yuris.Decompile(file.Script, compiler, file.Labels, yuris.Options{})
+		for _, warning := range warnings {
+			opts.warn("%s: %s", file.Path, warning)
+		}
+
+		segments = append(segments, analysis.Segments(lines, file.Id)...)
+	}
+
+	return segments, nil
+}
+
+func (opts *options) loadDirectory(dir string) ([]scriptFile, yuris.CompilerDefinition, error) {
+	compiler := yuris.CompilerDefinition{}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, compiler, err
+	} else if !info.IsDir() {
+		return nil, compiler, usageError("%s is not a directory or a YPF archive", dir)
+	}
+
+	inputs, err := expandInputs([]string{dir})
+	if err != nil {
+		return nil, compiler, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, compiler, usageError("no scripts found in %s", dir)
+	}
+
+	compiler, err = opts.readCompiler(inputs[0])
+	if err != nil {
+		return nil, compiler, err
+	}
+
+	files := make([]scriptFile, 0, len(inputs))
+	for _, input := range inputs {
+		file, err := opts.loadScript(input)
+		if err != nil {
+			return nil, compiler, err
+		}
+		files = append(files, file)
+	}
+
+	return files, compiler, nil
+}
+
+// Reads the scripts and ysl.ybn from the ysbin directory of a YPF archive.
+// YSCom.ycd is read from -yscom, the archive, or the directory of the
+// archive, in that order.
+func (opts *options) loadArchive(archivePath string) ([]scriptFile, yuris.CompilerDefinition, error) {
+	compiler := yuris.CompilerDefinition{}
+
+	opts.logf("reading %s", archivePath)
+	archive, err := yuris.OpenArchive(archivePath)
+	if err != nil {
+		return nil, compiler, fmt.Errorf("%s: %w", archivePath, err)
+	}
+	defer archive.Close()
+
+	read := func(entry yuris.ArchiveEntry) ([]byte, error) {
+		opts.logf("reading %s:%s", archivePath, entry.Name)
+		data, err := archive.Read(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", archivePath, err)
+		}
+		return data, nil
+	}
+
+	entry, ok := archive.Find("ysbin/YSCom.ycd")
+	if !ok {
+		entry, ok = archive.Find("YSCom.ycd")
+	}
+
+	if ok && opts.yscom == "" {
+		var data []byte
+		data, err = read(entry)
+		if err == nil {
+			compiler, err = opts.parseCompiler(archivePath + ":" + entry.Name, data)
+		}
+	} else {
+		compiler, err = opts.readCompiler(archivePath)
+	}
+	if err != nil {
+		return nil, compiler, err
+	}
+
+	entry, ok = archive.Find("ysbin/ysl.ybn")
+	if !ok {
+		return nil, compiler, fmt.Errorf("%s: ysbin/ysl.ybn not found", archivePath)
+	}
+
+	data, err := read(entry)
+	if err != nil {
+		return nil, compiler, err
+	}
+
+	labels, err := yuris.ParseYSL(data)
+	if err != nil {
+		return nil, compiler, fmt.Errorf("%s:%s: %w", archivePath, entry.Name, err)
+	}
+
+	files := make([]scriptFile, 0)
+	for _, entry := range archive.Entries {
+		name := strings.ReplaceAll(entry.Name, "\\", "/")
+		if !strings.EqualFold(path.Dir(name), "ysbin") {
+			continue
+		}
+
+		id, err := scriptId(strings.ToLower(path.Base(name)))
+		if err != nil {
+			continue
+		}
+
+		data, err := read(entry)
+		if err != nil {
+			return nil, compiler, err
+		}
+
+		file := scriptFile{Path: archivePath + ":" + entry.Name, Id: id}
+		file.Script, err = opts.parseScript(data)
+		if err != nil {
+			return nil, compiler, fmt.Errorf("%s: %w", file.Path, err)
+		}
+		file.Labels = yuris.ScriptLabels(labels, id)
+
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return nil, compiler, usageError("no scripts found in %s", archivePath)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Id < files[j].Id
+	})
+
+	return files, compiler, nil
+}
+
+func writeLabelDiff(w io.Writer, diff analysis.LabelDiff) {
+	switch diff.Kind {
+	case analysis.Added:
+		fmt.Fprintf(w, "#=%s added in yst%05d\n", diff.Name, diff.NewScript)
+	case analysis.Removed:
+		fmt.Fprintf(w, "#=%s removed from yst%05d\n", diff.Name, diff.OldScript)
+	default:
+		if diff.OldScript != diff.NewScript {
+			fmt.Fprintf(w, "#=%s moved from yst%05d to yst%05d\n", diff.Name, diff.OldScript, diff.NewScript)
+		} else {
+			fmt.Fprintf(w, "#=%s in yst%05d\n", diff.Name, diff.NewScript)
+		}
+	}
+
+	for _, change := range diff.Changes {
+		switch change.Kind {
+		case analysis.Added:
+			fmt.Fprintf(w, "  + %s\n", change.New)
+		case analysis.Removed:
+			fmt.Fprintf(w, "  - %s\n", change.Old)
+		default:
+			fmt.Fprintf(w, "  ~ %s changed\n", change.Kind)
+			fmt.Fprintf(w, "    - %s\n", change.Old)
+			fmt.Fprintf(w, "    + %s\n", change.New)
+		}
+	}
+
+	fmt.Fprintln(w)
+}
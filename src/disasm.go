@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type disasmAttribute struct {
+	Id			int16		`json:"id"`
+	Name		string		`json:"name"`
+	Type		string		`json:"type"`
+	Tokens		[]string	`json:"tokens"`
+	Value		string		`json:"value"`
+}
+
+type disasmCommand struct {
+	Index		int					`json:"index"`
+	Id			byte				`json:"id"`
+	Name		string				`json:"name"`
+	Attributes	[]disasmAttribute	`json:"attributes"`
+}
+
+func runDisasm(args []string) error {
+	opts := options{}
+	fs := newFlagSet("disasm", "<yst00xxx.ybn>", "Lists the commands and raw attribute values of a script", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 1 {
+		return usageError("expected a single yst00xxx.ybn path")
+	}
+
+	path := opts.args[0]
+	compiler, err := opts.readCompiler(path)
+	if err != nil {
+		return err
+	}
+
+	script, err := opts.readScript(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	commands := disassemble(script, compiler)
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, commands)
+		}
+
+		for _, command := range commands {
+			fmt.Fprintf(w, "%6d  %-16s id=%d\n", command.Index, command.Name, command.Id)
+			for _, attr := range command.Attributes {
+				fmt.Fprintf(w, "        %-16s type=%s  %s\n", attr.Name, attr.Type, strings.Join(attr.Tokens, " "))
+				fmt.Fprintf(w, "        %-16s %s\n", "", attr.Value)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+func disassemble(script yuris.Script, compiler yuris.CompilerDefinition) []disasmCommand {
+	commands := make([]disasmCommand, 0, len(script.Commands))
+	for i, command := range script.Commands {
+		item := disasmCommand{}
+		item.Index = i
+		item.Id = command.Id
+		item.Name = compiler.Commands[command.Id]
+
+		for _, attribute := range script.CommandAttributes(command) {
+			attr := disasmAttribute{}
+			attr.Id = attribute.Id
+			attr.Name = compiler.Definition(command.Id, attribute.Id).Name
+			attr.Type = fmt.Sprintf("%02x%02x", attribute.Type[0], attribute.Type[1])
+			attr.Value = attribute.Decompile()
+			for _, token := range yuris.Tokenize(attribute.Bytes) {
+				attr.Tokens = append(attr.Tokens, formatToken(token))
+			}
+
+			item.Attributes = append(item.Attributes, attr)
+		}
+
+		commands = append(commands, item)
+	}
+
+	return commands
+}
+
+// Formats a token as its opcode character followed by its operand bytes.
+func formatToken(token yuris.Token) string {
+	opcode := fmt.Sprintf("%02x", token.Opcode)
+	if token.Opcode >= 0x21 && token.Opcode < 0x7f {
+		opcode = string(rune(token.Opcode))
+	}
+
+	if len(token.Operand) == 0 {
+		return opcode
+	}
+
+	return fmt.Sprintf("%s(%x)", opcode, token.Operand)
+}
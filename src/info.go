@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type commandCount struct {
+	Name			string	`json:"name"`
+	Id				byte	`json:"id"`
+	Count			int		`json:"count"`
+}
+
+type scriptInfo struct {
+	Path			string			`json:"path"`
+	Id				int				`json:"id"`
+	Header			yuris.Header	`json:"header"`
+	Encrypted		bool			`json:"encrypted"`
+	Attributes		int				`json:"attributes"`
+	Labels			[]yuris.Label	`json:"labels"`
+	Commands		[]commandCount	`json:"commands"`
+}
+
+func runInfo(args []string) error {
+	opts := options{}
+	fs := newFlagSet("info", "<yst00xxx.ybn|->", "Shows the header, labels and command frequencies of a script", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 1 {
+		return usageError("expected a single yst00xxx.ybn path")
+	}
+
+	file, err := opts.loadScript(opts.args[0])
+	if err != nil {
+		return err
+	}
+
+	compiler, err := opts.readCompiler(file.Path)
+	if err != nil {
+		opts.warn("%v, showing command ids only", err)
+	}
+
+	info := scriptInfo{}
+	info.Path = file.Path
+	info.Id = file.Id
+	info.Header = file.Script.Header
+	info.Encrypted = file.Script.Header.Key != 0
+	info.Attributes = len(file.Script.Attributes)
+	info.Labels = file.Labels
+	info.Commands = commandHistogram(file.Script, compiler)
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, info)
+		}
+
+		header := info.Header
+		fmt.Fprintf(w, "Script:             %s\n", info.Path)
+		fmt.Fprintf(w, "Index:              %d\n", info.Id)
+		fmt.Fprintf(w, "Version:            %d\n", header.Version)
+		fmt.Fprintf(w, "Instructions:       %d\n", header.NumInstructions)
+		fmt.Fprintf(w, "Attributes:         %d\n", info.Attributes)
+		fmt.Fprintf(w, "Sections:\n")
+		fmt.Fprintf(w, "  Instructions:     %d bytes\n", header.InstructionsSize)
+		fmt.Fprintf(w, "  Attributes:       %d bytes\n", header.AttrDescriptorsSize)
+		fmt.Fprintf(w, "  Values:           %d bytes\n", header.AttrValuesSize)
+		fmt.Fprintf(w, "  Line numbers:     %d bytes\n", header.LineNumbersSize)
+		if info.Encrypted {
+			fmt.Fprintf(w, "Encrypted:          yes (key %08x)\n", header.Key)
+		} else {
+			fmt.Fprintf(w, "Encrypted:          no\n")
+		}
+
+		fmt.Fprintf(w, "Labels:             %d\n", len(info.Labels))
+		for _, label := range info.Labels {
+			fmt.Fprintf(w, "  #=%s (offset %d)\n", label.Name, label.Offset)
+		}
+
+		fmt.Fprintf(w, "Commands:\n")
+		for _, count := range info.Commands {
+			fmt.Fprintf(w, "  %-16s %d\n", count.Name, count.Count)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+// Counts the commands of a script, most frequent first.
+func commandHistogram(script yuris.Script, compiler yuris.CompilerDefinition) []commandCount {
+	counts := make(map[byte]int)
+	for _, command := range script.Commands {
+		counts[command.Id]++
+	}
+
+	histogram := make([]commandCount, 0, len(counts))
+	for id, count := range counts {
+		name, ok := compiler.Commands[id]
+		if !ok {
+			name = fmt.Sprintf("#%d", id)
+		}
+
+		histogram = append(histogram, commandCount{name, id, count})
+	}
+
+	sort.Slice(histogram, func(i, j int) bool {
+		if histogram[i].Count != histogram[j].Count {
+			return histogram[i].Count > histogram[j].Count
+		}
+		return histogram[i].Name < histogram[j].Name
+	})
+
+	return histogram
+}
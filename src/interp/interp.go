@@ -0,0 +1,496 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/cfg"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+var ErrStepLimit = errors.New("step limit reached, the script may not terminate")
+
+type Config struct {
+	// Commands whose attributes are printed as text.
+	TextCommands	[]string
+	// Commands whose string attributes are offered as choices.
+	ChoiceCommands	[]string
+	// Variable that receives the 1-based number of the chosen option.
+	ChoiceVariable	string
+	// Maximum number of commands to execute, unlimited if 0.
+	MaxSteps		int
+
+	Output			io.Writer
+	// Returns the 0-based index of the chosen option.
+	Choose			func(options []string) (int, error)
+	Symbols			*yuris.Symbols
+	// Reports commands that are executed only in part, ignored if nil.
+	Warn			func(format string, args ...any)
+}
+
+// A location in the command stream.
+type Position struct {
+	Script		int
+	Index		int
+}
+
+// An error raised while executing a command.
+type RuntimeError struct {
+	Position	Position
+	Command		string
+	Err			error
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("yst%05d:%d %s: %v", e.Position.Script, e.Position.Index, e.Command, e.Err)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+type program struct {
+	script		yuris.Script
+	structure	cfg.Structure
+	labelsAt	map[int][]string
+}
+
+// Executes scripts without the engine. Text commands are printed, choices
+// are asked through Config.Choose, and commands without an effect on the
+// script logic, such as graphics and sound, do nothing.
+type Interpreter struct {
+	Config		Config
+	Vars		*Variables
+	// Number of times each label was reached.
+	Reached		map[string]int
+
+	compiler	yuris.CompilerDefinition
+	labels		map[string]yuris.Label
+	programs	map[int]*program
+	text		map[string]bool
+	choices		map[string]bool
+
+	pc			Position
+	calls		[]Position
+	loops		map[Position]int
+	warned		map[Position]bool
+	steps		int
+	done		bool
+}
+
+// Returns an interpreter for the given compiler definition and the labels of
+// ysl.ybn.
+func New(compiler yuris.CompilerDefinition, labels []yuris.Label, config Config) *Interpreter {
+	in := &Interpreter{}
+	in.Config = config
+	in.Vars = NewVariables()
+	in.Reached = make(map[string]int)
+	in.compiler = compiler
+	in.programs = make(map[int]*program)
+	in.loops = make(map[Position]int)
+	in.warned = make(map[Position]bool)
+
+	in.labels = make(map[string]yuris.Label)
+	for _, label := range labels {
+		in.labels[label.Name] = label
+	}
+
+	in.text = make(map[string]bool)
+	for _, name := range config.TextCommands {
+		in.text[name] = true
+	}
+
+	in.choices = make(map[string]bool)
+	for _, name := range config.ChoiceCommands {
+		in.choices[name] = true
+	}
+
+	if in.Config.Output == nil {
+		in.Config.Output = io.Discard
+	}
+
+	return in
+}
+
+// Clears the variables and the reached labels.
+func (in *Interpreter) Reset() {
+	in.Vars = NewVariables()
+	in.Reached = make(map[string]int)
+	in.loops = make(map[Position]int)
+	in.steps = 0
+}
+
+func (in *Interpreter) AddScript(scriptIndex int, script yuris.Script) {
+	p := &program{}
+	p.script = script
+	p.structure = cfg.Analyze(script, in.compiler)
+	p.labelsAt = make(map[int][]string)
+	for _, label := range in.labels {
+		if int(label.ScriptIndex) == scriptIndex {
+			p.labelsAt[label.Offset] = append(p.labelsAt[label.Offset], label.Name)
+		}
+	}
+
+	in.programs[scriptIndex] = p
+}
+
+// Runs from the given label until END, a RETURN with no caller, or the end
+// of the script.
+func (in *Interpreter) Run(label string) error {
+	target, ok := in.labels[label]
+	if !ok {
+		return fmt.Errorf("label %s not found", label)
+	}
+
+	return in.RunAt(Position{int(target.ScriptIndex), target.Offset})
+}
+
+// Runs from the given command.
+func (in *Interpreter) RunAt(start Position) error {
+	in.pc = start
+	in.calls = nil
+	in.done = false
+
+	for !in.done {
+		if in.Config.MaxSteps > 0 && in.steps >= in.Config.MaxSteps {
+			return ErrStepLimit
+		}
+		in.steps++
+
+		if err := in.step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (in *Interpreter) step() error {
+	p, ok := in.programs[in.pc.Script]
+	if !ok {
+		return fmt.Errorf("script %d is not loaded", in.pc.Script)
+	}
+
+	for _, name := range p.labelsAt[in.pc.Index] {
+		in.Reached[name]++
+	}
+
+	if in.pc.Index >= len(p.script.Commands) {
+		in.ret()
+		return nil
+	}
+
+	at := in.pc
+	command := p.script.Commands[at.Index]
+	name := p.structure.Names[at.Index]
+	if err := in.execute(p, name, p.script.CommandAttributes(command)); err != nil {
+		return &RuntimeError{at, name, err}
+	}
+
+	return nil
+}
+
+func (in *Interpreter) execute(p *program, name string, attributes []yuris.Attribute) error {
+	s := p.structure
+	at := in.pc
+	in.pc.Index++
+
+	switch {
+	case in.text[name]:
+		return in.printText(attributes)
+	case in.choices[name]:
+		return in.choose(attributes)
+	case yuris.IsDeclaration(name):
+		return in.declare(attributes)
+	}
+
+	switch name {
+	case "IF":
+		isTrue, err := in.condition(attributes)
+		if err != nil || isTrue {
+			return err
+		}
+		return in.skipBranch(s, at.Index)
+	case "ELSE", "IFBLEND":
+		// The previous branch was taken.
+		end, ok := s.BranchEnd[at.Index]
+		if !ok {
+			return errors.New("no matching IFEND")
+		}
+		in.pc.Index = end + 1
+	case "LOOP":
+		count := int64(-1)
+		if len(attributes) > 0 {
+			value, err := attributes[0].Evaluate(in.Vars)
+			if err != nil {
+				return err
+			}
+			count = value.ToNumber().Int
+		}
+
+		if count == 0 {
+			return in.exitLoop(s, at.Index)
+		}
+		in.loops[at] = int(count)
+	case "LOOPEND":
+		head, ok := s.LoopHead[at.Index]
+		if !ok {
+			return errors.New("no matching LOOP")
+		}
+
+		key := Position{at.Script, head}
+		remaining := in.loops[key]
+		if remaining > 0 {
+			remaining--
+		}
+
+		if remaining != 0 {
+			in.loops[key] = remaining
+			in.pc.Index = head + 1
+		} else {
+			delete(in.loops, key)
+		}
+	case "LOOPBREAK":
+		head, ok := s.LoopHead[at.Index]
+		if !ok {
+			return errors.New("LOOPBREAK outside of a LOOP")
+		}
+		return in.exitLoop(s, head)
+	case "LOOPCONTINUE":
+		head, ok := s.LoopHead[at.Index]
+		if !ok {
+			return errors.New("LOOPCONTINUE outside of a LOOP")
+		}
+
+		end, ok := s.LoopEnd[head]
+		if !ok {
+			return errors.New("no matching LOOPEND")
+		}
+		in.pc.Index = end
+	case "GOTO":
+		return in.jump(attributes)
+	case "GOSUB":
+		if err := in.checkParameters(p.script.Commands[at.Index], attributes); err != nil {
+			return err
+		}
+
+		in.calls = append(in.calls, in.pc)
+		return in.jump(attributes)
+	case "RETURN":
+		in.ret()
+	case "END":
+		in.done = true
+	case "LET":
+		return in.let(attributes)
+	}
+
+	return nil
+}
+
+// Moves to the first branch after the IF or ELSE at index whose condition
+// holds, or past the IFEND.
+func (in *Interpreter) skipBranch(s cfg.Structure, index int) error {
+	for {
+		next, ok := s.BranchNext[index]
+		if !ok {
+			return errors.New("no matching IFEND")
+		}
+
+		in.pc.Index = next + 1
+		if s.Names[next] != "ELSE" {
+			return nil
+		}
+
+		command := in.programs[in.pc.Script].script.Commands[next]
+		isTrue, err := in.condition(in.programs[in.pc.Script].script.CommandAttributes(command))
+		if err != nil || isTrue {
+			return err
+		}
+
+		index = next
+	}
+}
+
+func (in *Interpreter) exitLoop(s cfg.Structure, head int) error {
+	end, ok := s.LoopEnd[head]
+	if !ok {
+		return errors.New("no matching LOOPEND")
+	}
+
+	delete(in.loops, Position{in.pc.Script, head})
+	in.pc.Index = end + 1
+	return nil
+}
+
+// Evaluates the condition of an IF or ELSE. An ELSE without a condition
+// always holds.
+func (in *Interpreter) condition(attributes []yuris.Attribute) (bool, error) {
+	if len(attributes) == 0 {
+		return true, nil
+	}
+
+	value, err := attributes[0].Evaluate(in.Vars)
+	if err != nil {
+		return false, err
+	}
+
+	return value.IsTrue(), nil
+}
+
+func (in *Interpreter) jump(attributes []yuris.Attribute) error {
+	if len(attributes) == 0 {
+		return errors.New("missing label")
+	}
+
+	value, err := attributes[0].Evaluate(in.Vars)
+	if err != nil {
+		return err
+	}
+
+	name := yuris.LabelName(value.String())
+	label, ok := in.labels[name]
+	if !ok {
+		return fmt.Errorf("label %s not found", name)
+	}
+
+	in.pc = Position{int(label.ScriptIndex), label.Offset}
+	return nil
+}
+
+// Warns about GOSUB parameters, such as PINT and PSTR, that are not 0 or an
+// empty string. They are not passed to the subroutine, so the values it reads
+// may differ from the engine. Each GOSUB is reported once.
+func (in *Interpreter) checkParameters(command yuris.Command, attributes []yuris.Attribute) error {
+	at := Position{in.pc.Script, in.pc.Index - 1}
+	if in.Config.Warn == nil || in.warned[at] || len(attributes) < 2 {
+		return nil
+	}
+
+	for _, attribute := range attributes[1:] {
+		value, err := attribute.Evaluate(in.Vars)
+		if err != nil {
+			return err
+		}
+
+		if value.IsTrue() {
+			name := in.compiler.Definition(command.Id, attribute.Id).Name
+			in.Config.Warn("yst%05d:%d GOSUB: parameter %s=%s is not passed to the subroutine", at.Script, at.Index, name, value.String())
+			in.warned[at] = true
+		}
+	}
+
+	return nil
+}
+
+func (in *Interpreter) ret() {
+	if len(in.calls) == 0 {
+		in.done = true
+		return
+	}
+
+	in.pc = in.calls[len(in.calls) - 1]
+	in.calls = in.calls[:len(in.calls) - 1]
+}
+
+func (in *Interpreter) let(attributes []yuris.Attribute) error {
+	if len(attributes) < 2 {
+		return errors.New("missing operand")
+	}
+
+	prefix, id, indices, err := yuris.EvaluateTarget(yuris.Tokenize(attributes[0].Bytes), in.Vars)
+	if err != nil {
+		return err
+	}
+
+	value, err := attributes[1].Evaluate(in.Vars)
+	if err != nil {
+		return err
+	}
+
+	current, _ := in.Vars.Get(prefix, id, indices)
+	value, err = yuris.Assign(attributes[0].Type[1], current, value)
+	if err != nil {
+		return err
+	}
+
+	return in.Vars.Set(prefix, id, indices, value)
+}
+
+// Declares a variable with its initial value. Array sizes are ignored since
+// elements are created when they are assigned.
+func (in *Interpreter) declare(attributes []yuris.Attribute) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	tokens := yuris.Tokenize(attributes[0].Bytes)
+	if len(tokens) == 0 || !tokens[0].IsVariable() {
+		return errors.New("declaration without a variable")
+	}
+
+	prefix, id := tokens[0].Variable()
+	value := yuris.DefaultValue(prefix)
+	if len(attributes) > 1 {
+		var err error
+		value, err = attributes[1].Evaluate(in.Vars)
+		if err != nil {
+			return err
+		}
+	}
+
+	return in.Vars.Set(prefix, id, nil, value)
+}
+
+func (in *Interpreter) printText(attributes []yuris.Attribute) error {
+	parts := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		value, err := attribute.Evaluate(in.Vars)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, value.String())
+	}
+
+	_, err := fmt.Fprintln(in.Config.Output, strings.Join(parts, " "))
+	return err
+}
+
+func (in *Interpreter) choose(attributes []yuris.Attribute) error {
+	options := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		value, err := attribute.Evaluate(in.Vars)
+		if err != nil {
+			return err
+		}
+
+		if value.Type == yuris.TypeString {
+			options = append(options, value.Str)
+		}
+	}
+
+	if in.Config.Choose == nil {
+		return errors.New("no way to choose an option")
+	}
+
+	choice, err := in.Config.Choose(options)
+	if err != nil {
+		return err
+	}
+
+	if choice < 0 || choice >= len(options) {
+		return fmt.Errorf("invalid choice %d of %d options", choice + 1, len(options))
+	}
+
+	if in.Config.ChoiceVariable == "" {
+		return nil
+	}
+
+	prefix, id, ok := in.Config.Symbols.FindVariable(in.Config.ChoiceVariable)
+	if !ok {
+		return fmt.Errorf("unknown choice variable %s", in.Config.ChoiceVariable)
+	}
+
+	return in.Vars.Set(prefix, id, nil, yuris.IntValue(int64(choice + 1)))
+}
@@ -0,0 +1,271 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// Comparison operators of expect assertions, by their opcode.
+var comparisons = map[string]byte{
+	"==":	0x3d,
+	"!=":	0x21,
+	"<":	0x3c,
+	">":	0x3e,
+	"<=":	0x53,
+	">=":	0x5a,
+}
+
+// Splits an expect assertion into the variable, the operator and the value.
+// The variable may contain spaces between its indices, as in @var2(1, 3),
+// and the two character operators are tried before < and >.
+var expectPattern = regexp.MustCompile(`^(\S.*?)\s*(==|!=|<=|>=|<|>)\s*(.+)$`)
+
+// A check made after a scenario has run.
+type Assertion struct {
+	Line		int
+	Kind		string
+	Variable	string
+	Operator	string
+	Value		yuris.Value
+	Label		string
+}
+
+// A play-through with a fixed sequence of choices, followed by assertions on
+// the variables and the labels that were reached.
+type Scenario struct {
+	Name		string
+	Line		int
+	Start		string
+	Choices		[]int
+	Assertions	[]Assertion
+}
+
+// The outcome of a scenario. Failures is empty if it passed.
+type ScenarioResult struct {
+	Name		string		`json:"name"`
+	Failures	[]string	`json:"failures"`
+}
+
+func ReadScenarios(path string) ([]Scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseScenarios(file)
+}
+
+// Parses a test file. Each scenario starts with a test line, and blank
+// lines and lines starting with # are ignored:
+//
+//	test good ending
+//	start MAIN
+//	choose 2 1
+//	expect @var1 == 7
+//	expect $var2 != "abc"
+//	expect @var3(1, 2) >= 0
+//	reached TAIL
+//	not-reached ORPHAN
+func ParseScenarios(r io.Reader) ([]Scenario, error) {
+	scenarios := make([]Scenario, 0)
+	scanner := bufio.NewScanner(r)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		keyword := fields[0]
+		rest := strings.TrimSpace(strings.TrimPrefix(line, keyword))
+
+		if keyword == "test" {
+			scenarios = append(scenarios, Scenario{Name: rest, Line: lineNumber})
+			continue
+		}
+
+		if len(scenarios) == 0 {
+			return nil, fmt.Errorf("line %d: %s before the first test", lineNumber, keyword)
+		}
+
+		scenario := &scenarios[len(scenarios) - 1]
+		switch keyword {
+		case "start":
+			scenario.Start = rest
+		case "choose":
+			for _, field := range fields[1:] {
+				choice, err := strconv.Atoi(field)
+				if err != nil || choice < 1 {
+					return nil, fmt.Errorf("line %d: invalid choice %q", lineNumber, field)
+				}
+				scenario.Choices = append(scenario.Choices, choice)
+			}
+		case "expect":
+			match := expectPattern.FindStringSubmatch(rest)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: expected expect VARIABLE OPERATOR VALUE", lineNumber)
+			}
+
+			value, err := parseValue(match[3])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+
+			assertion := Assertion{Line: lineNumber, Kind: keyword}
+			assertion.Variable = match[1]
+			assertion.Operator = match[2]
+			assertion.Value = value
+			scenario.Assertions = append(scenario.Assertions, assertion)
+		case "reached", "not-reached":
+			if rest == "" {
+				return nil, fmt.Errorf("line %d: missing label", lineNumber)
+			}
+
+			scenario.Assertions = append(scenario.Assertions, Assertion{Line: lineNumber, Kind: keyword, Label: rest})
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %s", lineNumber, keyword)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+// Parses a quoted string, an integer or a double.
+func parseValue(text string) (yuris.Value, error) {
+	if strings.HasPrefix(text, "\"") {
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return yuris.Value{}, fmt.Errorf("invalid string %s", text)
+		}
+		return yuris.StringValue(s), nil
+	}
+
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return yuris.IntValue(n), nil
+	}
+
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return yuris.DoubleValue(n), nil
+	}
+
+	return yuris.Value{}, fmt.Errorf("invalid value %s", text)
+}
+
+// Runs the scenario on a reset interpreter, answering the choices in order,
+// and checks its assertions.
+func (in *Interpreter) RunScenario(scenario Scenario) ScenarioResult {
+	result := ScenarioResult{Name: scenario.Name}
+	fail := func(format string, args ...any) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	in.Reset()
+
+	choices := scenario.Choices
+	in.Config.Choose = func(options []string) (int, error) {
+		if len(choices) == 0 {
+			return 0, fmt.Errorf("ran out of choices, offered %q", options)
+		}
+
+		choice := choices[0]
+		choices = choices[1:]
+		return choice - 1, nil
+	}
+
+	var err error
+	if scenario.Start != "" {
+		err = in.Run(scenario.Start)
+	} else {
+		err = in.RunAt(Position{})
+	}
+
+	if err != nil {
+		fail("line %d: %v", scenario.Line, err)
+		return result
+	}
+
+	if len(choices) > 0 {
+		fail("line %d: %d choices were not used", scenario.Line, len(choices))
+	}
+
+	for _, assertion := range scenario.Assertions {
+		if message := in.check(assertion); message != "" {
+			fail("line %d: %s", assertion.Line, message)
+		}
+	}
+
+	return result
+}
+
+// Returns why the assertion does not hold, or an empty string.
+func (in *Interpreter) check(assertion Assertion) string {
+	switch assertion.Kind {
+	case "reached":
+		if in.Reached[assertion.Label] == 0 {
+			return fmt.Sprintf("label %s was not reached", assertion.Label)
+		}
+		return ""
+	case "not-reached":
+		if in.Reached[assertion.Label] > 0 {
+			return fmt.Sprintf("label %s was reached", assertion.Label)
+		}
+		return ""
+	}
+
+	actual, err := in.lookup(assertion.Variable)
+	if err != nil {
+		return err.Error()
+	}
+
+	result, err := yuris.Operate(comparisons[assertion.Operator], actual, assertion.Value)
+	if err != nil {
+		return err.Error()
+	}
+
+	if !result.IsTrue() {
+		return fmt.Sprintf("expected %s %s %s, got %s", assertion.Variable, assertion.Operator, assertion.Value, actual)
+	}
+
+	return ""
+}
+
+// Returns the value of a variable given by name, with optional indices such
+// as @var2(1, 3).
+func (in *Interpreter) lookup(name string) (yuris.Value, error) {
+	base := name
+	indices := make([]int64, 0)
+	if open := strings.Index(name, "("); open >= 0 && strings.HasSuffix(name, ")") {
+		base = name[:open]
+		for _, part := range strings.Split(name[open + 1:len(name) - 1], ",") {
+			index, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return yuris.Value{}, fmt.Errorf("invalid index in %s", name)
+			}
+			indices = append(indices, index)
+		}
+	}
+
+	prefix, id, ok := in.Config.Symbols.FindVariable(base)
+	if !ok {
+		return yuris.Value{}, fmt.Errorf("unknown variable %s", base)
+	}
+
+	value, _ := in.Vars.Get(prefix, id, indices)
+	return value, nil
+}
@@ -0,0 +1,80 @@
+package interp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func TestParseScenarios(t *testing.T) {
+	input := `# endings
+test good ending
+start MAIN
+choose 2 1
+expect @var1 == 7
+expect @var2(1, 3) == 0
+expect @var3<=-1
+expect $var4 != "a < b"
+expect @var5 > 1.5
+reached TAIL
+
+test bad ending
+not-reached GOOD END
+`
+
+	want := []Scenario{
+		{
+			Name:		"good ending",
+			Line:		2,
+			Start:		"MAIN",
+			Choices:	[]int{2, 1},
+			Assertions:	[]Assertion{
+				{Line: 5, Kind: "expect", Variable: "@var1", Operator: "==", Value: yuris.IntValue(7)},
+				{Line: 6, Kind: "expect", Variable: "@var2(1, 3)", Operator: "==", Value: yuris.IntValue(0)},
+				{Line: 7, Kind: "expect", Variable: "@var3", Operator: "<=", Value: yuris.IntValue(-1)},
+				{Line: 8, Kind: "expect", Variable: "$var4", Operator: "!=", Value: yuris.StringValue("a < b")},
+				{Line: 9, Kind: "expect", Variable: "@var5", Operator: ">", Value: yuris.DoubleValue(1.5)},
+				{Line: 10, Kind: "reached", Label: "TAIL"},
+			},
+		},
+		{
+			Name:		"bad ending",
+			Line:		12,
+			Assertions:	[]Assertion{
+				{Line: 13, Kind: "not-reached", Label: "GOOD END"},
+			},
+		},
+	}
+
+	got, err := ParseScenarios(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseScenarios() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseScenariosErrors(t *testing.T) {
+	tests := []struct {
+		name		string
+		input		string
+	}{
+		{"before the first test", "start MAIN"},
+		{"unknown keyword", "test a\nplay MAIN"},
+		{"invalid choice", "test a\nchoose 0"},
+		{"missing operator", "test a\nexpect @var1 7"},
+		{"missing value", "test a\nexpect @var1 =="},
+		{"invalid value", "test a\nexpect @var1 == seven"},
+		{"invalid string", "test a\nexpect $var1 == \"abc"},
+		{"missing label", "test a\nreached"},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseScenarios(strings.NewReader(test.input)); err == nil {
+			t.Errorf("%s: ParseScenarios() succeeded", test.name)
+		}
+	}
+}
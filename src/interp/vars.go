@@ -0,0 +1,77 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type variable struct {
+	Name		string
+	Value		yuris.Value
+}
+
+// Typed variable storage. @ variables hold numbers and $ variables hold
+// strings. Variables that were never assigned read as 0 or "".
+type Variables struct {
+	values		map[string]variable
+}
+
+func NewVariables() *Variables {
+	return &Variables{make(map[string]variable)}
+}
+
+func variableName(prefix string, id int16, indices []int64) string {
+	name := yuris.VariableKey(prefix, id)
+	if len(indices) == 0 {
+		return name
+	}
+
+	parts := make([]string, len(indices))
+	for i, index := range indices {
+		parts[i] = fmt.Sprintf("%d", index)
+	}
+
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+func (v *Variables) Get(prefix string, id int16, indices []int64) (yuris.Value, bool) {
+	entry, ok := v.values[variableName(prefix, id, indices)]
+	if !ok {
+		return yuris.DefaultValue(prefix), true
+	}
+
+	return entry.Value, true
+}
+
+func (v *Variables) Set(prefix string, id int16, indices []int64, value yuris.Value) error {
+	switch {
+	case prefix == "$" && value.Type != yuris.TypeString:
+		value = value.ToString()
+	case prefix != "$" && value.Type == yuris.TypeString:
+		return fmt.Errorf("cannot assign string %q to %s", value.Str, yuris.VariableKey(prefix, id))
+	}
+
+	name := variableName(prefix, id, indices)
+	v.values[name] = variable{name, value}
+	return nil
+}
+
+// Returns the assigned variables, sorted by name.
+func (v *Variables) Names() []string {
+	names := make([]string, 0, len(v.values))
+	for name := range v.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Returns the value of an assigned variable or array element by name.
+func (v *Variables) Lookup(name string) (yuris.Value, bool) {
+	entry, ok := v.values[name]
+	return entry.Value, ok
+}
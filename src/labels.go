@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"regexp"
+	"strconv"
+	"strings"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runLabels(args []string) error {
+	opts := options{}
+	fs := newFlagSet("labels", "<ysl.ybn|ysbin dir>", "Lists and edits the labels of ysl.ybn", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	script := fs.Int("script", -1, "only list labels of this script index")
+	match := fs.String("match", "", "only list labels whose name matches this regular expression")
+
+	adds := stringList{}
+	renames := stringList{}
+	moves := stringList{}
+	fs.Var(&adds, "add", "add a label, as NAME@SCRIPT:OFFSET (repeatable)")
+	fs.Var(&renames, "rename", "rename a label, as OLD=NEW (repeatable)")
+	fs.Var(&moves, "move", "move a label, as NAME@SCRIPT:OFFSET (repeatable)")
+	write := fs.String("write", "", "write the edited ysl.ybn to this path")
+	check := fs.Bool("check", false, "check names against ids, duplicates, hash buckets and offsets against the scripts")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 1 {
+		return usageError("expected a single ysl.ybn path")
+	}
+
+	var pattern *regexp.Regexp
+	if *match != "" {
+		var err error
+		pattern, err = regexp.Compile(*match)
+		if err != nil {
+			return usageError("invalid -match: %v", err)
+		}
+	}
+
+	isEditing := len(adds) + len(renames) + len(moves) > 0
+	if isEditing && *write == "" {
+		return usageError("-add, -rename and -move require -write")
+	}
+
+	path := opts.args[0]
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "ysl.ybn")
+	}
+
+	opts.logf("reading %s", path)
+	table, err := yuris.ReadLabelTable(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if *check {
+		return checkLabels(&opts, table, filepath.Dir(path))
+	}
+
+	if *write != "" {
+		if err := editLabels(&table, adds, renames, moves); err != nil {
+			return err
+		}
+
+		opts.logf("writing %s", *write)
+		if err := yuris.WriteLabelTable(*write, table); err != nil {
+			return outputError(err)
+		}
+
+		return opts.finish()
+	}
+
+	labels := make([]yuris.Label, 0, len(table.Labels))
+	for _, label := range table.Labels {
+		if *script >= 0 && int(label.ScriptIndex) != *script {
+			continue
+		}
+
+		if pattern != nil && !pattern.MatchString(label.Name) {
+			continue
+		}
+
+		labels = append(labels, label)
+	}
+
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].ScriptIndex != labels[j].ScriptIndex {
+			return labels[i].ScriptIndex < labels[j].ScriptIndex
+		}
+		return labels[i].Offset < labels[j].Offset
+	})
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, labels)
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tID\tSCRIPT\tOFFSET")
+		for _, label := range labels {
+			fmt.Fprintf(tw, "%s\t%08x\t%d\t%d\n", label.Name, uint32(label.Id), label.ScriptIndex, label.Offset)
+		}
+
+		return tw.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+// Prints the problems found in the label table, using the scripts in the
+// ysbin directory to check label offsets.
+func checkLabels(opts *options, table yuris.LabelTable, ysbinPath string) error {
+	inputs, err := expandInputs([]string{ysbinPath})
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[int]int)
+	for _, input := range inputs {
+		id, err := scriptId(input)
+		if err != nil {
+			return err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		counts[id] = len(script.Commands)
+	}
+
+	if len(inputs) == 0 {
+		opts.warn("no scripts found in %s, not checking label offsets", ysbinPath)
+		counts = nil
+	}
+
+	if hash, ok := table.DetectHash(); ok {
+		opts.logf("label ids are %s hashes of the names", hash.Name)
+	} else if len(table.Labels) > 0 {
+		opts.warn("label ids do not match a known name hash, not checking them against the names")
+	}
+
+	problems := table.Validate(counts)
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, problems)
+		}
+
+		for _, problem := range problems {
+			fmt.Fprintln(w, problem)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return &exitError{exitInvalidInput, fmt.Errorf("%d problems in ysl.ybn", len(problems))}
+	}
+
+	return opts.finish()
+}
+
+func editLabels(table *yuris.LabelTable, adds []string, renames []string, moves []string) error {
+	if err := table.CheckLayout(); err != nil {
+		return &exitError{exitInvalidInput, fmt.Errorf("not editing ysl.ybn: %w", err)}
+	}
+
+	for _, add := range adds {
+		name, script, offset, err := parseLabelLocation(add)
+		if err != nil {
+			return err
+		}
+
+		if err := table.Add(name, script, offset); err != nil {
+			return &exitError{exitInvalidInput, err}
+		}
+	}
+
+	for _, rename := range renames {
+		parts := strings.SplitN(rename, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return usageError("invalid -rename %q, expected OLD=NEW", rename)
+		}
+
+		if err := table.Rename(parts[0], parts[1]); err != nil {
+			return &exitError{exitInvalidInput, err}
+		}
+	}
+
+	for _, move := range moves {
+		name, script, offset, err := parseLabelLocation(move)
+		if err != nil {
+			return err
+		}
+
+		if err := table.Move(name, script, offset); err != nil {
+			return usageError("%v", err)
+		}
+	}
+
+	return nil
+}
+
+// Parses a label location given as NAME@SCRIPT:OFFSET.
+func parseLabelLocation(value string) (string, int16, int, error) {
+	r, _ := regexp.Compile("^(.+)@(\\d+):(\\d+)$")
+	submatch := r.FindStringSubmatch(value)
+	if len(submatch) < 4 {
+		return "", 0, 0, usageError("invalid label %q, expected NAME@SCRIPT:OFFSET", value)
+	}
+
+	script, err := strconv.Atoi(submatch[2])
+	if err != nil || script > 0x7fff {
+		return "", 0, 0, usageError("invalid script index in %q", value)
+	}
+
+	offset, err := strconv.Atoi(submatch[3])
+	if err != nil {
+		return "", 0, 0, usageError("invalid offset in %q", value)
+	}
+
+	return submatch[1], int16(script), offset, nil
+}
@@ -3,272 +3,139 @@ package main
 import (
 	"fmt"
 	"os"
-	"bufio"
 	"log"
-	"regexp"
-	"strconv"
-	"path/filepath"
-	
-	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+	"flag"
+	"errors"
+	"strings"
+	"io/fs"
+
 	"github.com/damianfadri/yuris-decompiler/yuris"
 )
 
-func exists(path string) (bool, error) {
-	_, err := os.Stat(path)
-	if err == nil {
-		return true, nil
-	}
-
-	if os.IsNotExist(err) {
-		return false, nil
-	}
+// Exit codes, by error class.
+const (
+	exitOK				= 0
+	exitFailure			= 1
+	exitUsage			= 2
+	exitNotFound		= 3
+	exitInvalidInput	= 4
+	exitOutput			= 5
+	exitWarnings		= 6
+)
 
-	return false, err
+type subcommand struct {
+	name		string
+	summary		string
+	run			func(args []string) error
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Missing yst00xxx.ybn path.")
-	}
-
-	if len(os.Args) < 3 {
-		log.Fatal("Missing YSCom.ycd path.")
-	}
+var subcommands = []subcommand{
+	{"decompile", "Decompile scripts into YU-RIS source", runDecompile},
+	{"disasm", "List the commands and raw attribute values of a script", runDisasm},
+	{"extract-text", "Extract string literals from scripts", runExtractText},
+	{"labels", "List the labels of ysl.ybn", runLabels},
+	{"vars", "List the variables referenced by scripts", runVars},
+	{"info", "Show information about a script", runInfo},
+	{"symbols", "Generate a symbol file for variable and label names", runSymbols},
+	{"xref", "List where each variable is read and written", runXref},
+	{"routes", "Extract the scenes and choices of the story as a graph", runRoutes},
+	{"assets", "List the asset files referenced by scripts", runAssets},
+	{"run", "Play the script logic in the terminal", runRun},
+	{"test", "Check variables and reached labels after scripted choices", runTest},
+	{"deadcode", "List unused labels, unreachable code and constant conditions", runDeadCode},
+	{"diff", "Compare two versions of a game label by label", runDiff},
+	{"search", "Find decompiled commands with structured queries", runSearch},
+	{"cfg", "Write the control flow graph of a script", runCfg},
+}
 
-	if len(os.Args) < 4 {
-		log.Fatal("Missing output path.")
-	}
+// An error with the exit code of its class.
+type exitError struct {
+	code		int
+	err			error
+}
 
-	args := os.Args[1:]
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
 
-	scriptPath := args[0]
+func (e *exitError) Unwrap() error {
+	return e.err
+}
 
-	r, _ := regexp.Compile(".*yst0*(\\d+)\\.ybn")
-	submatch := r.FindStringSubmatch(scriptPath)
-	if len(submatch) < 2 {
-		log.Fatal("Invalid script file name.")
-	}
+func usageError(format string, args ...any) error {
+	return &exitError{exitUsage, fmt.Errorf(format, args...)}
+}
 
-	scriptId, err := strconv.Atoi(submatch[1])
-	if err != nil {
-		log.Fatal(err)
-	}
+func outputError(err error) error {
+	return &exitError{exitOutput, err}
+}
 
-	if isExists, _ := exists(scriptPath); !isExists {
-		log.Fatal("Script file does not exist.")
-	}
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("yuris-dec: ")
 
-	script := yuris.ReadYST(scriptPath)	
+	os.Exit(run(os.Args[1:]))
+}
 
-	ysbinPath := filepath.Dir(scriptPath)
-	labelsPath := filepath.Join(ysbinPath, "ysl.ybn")
-	if isExists, _ := exists(labelsPath); !isExists {
-		log.Fatal("ysl.ybn does not exist.")
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitUsage
 	}
 
-	labels := yuris.ReadYSL(labelsPath)
-
-	yscomPath := args[1]
-	if isExists, _ := exists(yscomPath); !isExists {
-		log.Fatal("YSCom.ycd does not exist.")
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		printUsage()
+		return exitOK
 	}
-	compiler := yuris.ReadYSCom(yscomPath)
 
-	// Get labels for the current script
-	scriptLabels := dsa.NewList[yuris.Label]()
-	for i := 0; i < len(labels); i++ {
-		label := labels[i]
-		if (label.ScriptIndex == int16(scriptId)) {
-			scriptLabels.Add(label)
+	for _, cmd := range subcommands {
+		if cmd.name == args[0] {
+			return exitCode(cmd.run(args[1:]))
 		}
 	}
 
-	iterCommands := dsa.NewIterator[yuris.Command](script.Commands)
-	iterAttributes := dsa.NewIterator[yuris.Attribute](script.Attributes)
-	iterLabels := dsa.NewIterator[yuris.Label](scriptLabels.Items)
-	
-	stack := dsa.NewStack[yuris.Line]()
-
-	label := iterLabels.Next()
-	command := iterCommands.Next()
-	commandCount := 0
-	for iterCommands.HasNext() {
-		item := yuris.Line{}
-
-		// TODO: Handle labels without return
-		if label != nil && commandCount == label.Offset {
-			args := dsa.NewList[string]()
-			args.Add(label.Name)
-
-			names := dsa.NewList[string]()
-			names.Add("LabelName")
-
-			item.Command = "LABEL"
-			item.Arguments = args.Items
-			item.Names = names.Items
-
-			stack.Push(item)
-
-			label = iterLabels.Next()
-		} else {
-			commandName := compiler.Commands[command.Id]
-			item.Command = commandName
-	
-			names := dsa.NewList[string]()
-			args := dsa.NewList[string]()
-	
-			// Set current line value.
-			switch commandName {
-			case "IF":
-				fallthrough
-			case "ELSE":
-				fallthrough
-			case "LOOP":
-				if command.NumAttributes == 0 {
-					break
-				}
-				attribute := iterAttributes.Next()
-				conditionAttr := compiler.Attributes[command.Id][byte(attribute.Id)]
-				conditionValue := attribute.Decompile()
-	
-				names.Add(conditionAttr)
-				args.Add(conditionValue)
-	
-				// Skip the rest of the attributes
-				for i := 1; i < int(command.NumAttributes); i++ {
-					iterAttributes.Next()
-				}
-			case "LET":	
-				varNameAttr := iterAttributes.Next()
-				varName := varNameAttr.Decompile()
-	
-				varValueAttr := iterAttributes.Next()
-				varValue := varValueAttr.Decompile()
-	
-				varOperation := "="
-				switch varNameAttr.Type[1] {
-				case byte(1):
-					varOperation = "+="
-				case byte(2):
-					varOperation = "-="
-				}
-	
-				names.Add("Operand1")
-				args.Add(varName)
-
-				names.Add("Operation")
-				args.Add(varOperation)
-
-				names.Add("Operand2")
-				args.Add(varValue)
-			default:
-				for i := 0; i < int(command.NumAttributes); i++ {
-					attribute := iterAttributes.Next()
-					attrName := compiler.Attributes[command.Id][byte(attribute.Id)]
-					attrValue := attribute.Decompile()
-	
-					names.Add(attrName)
-					args.Add(attrValue)
-				}
-			}
-	
-			item.Names = names.Items
-			item.Arguments = args.Items
-			
-			switch commandName {
-			case "RETURN":
-				curr := item
-				children := dsa.NewList[yuris.Line]()
-				for stack.Count() > 0 && (curr.Command != "LABEL" || curr.Visited) {
-					children.Add(curr)
-					curr = stack.Pop()
-				}
-	
-				children.Reverse()
-	
-				start := curr
-				start.Visited = true
-				start.Children = children.Items
-				stack.Push(start)
-			case "RETURNCODE":
-				curr := item
-				children := dsa.NewList[yuris.Line]()
-				for stack.Count() > 0 && (curr.Command != "WORD" || curr.Visited) {
-					children.Add(curr)
-					curr = stack.Pop()
-				}
-	
-				children.Reverse()
-	
-				start := curr
-				start.Visited = true
-				start.Children = children.Items
-				stack.Push(start)
-			case "IFBLEND":
-				fallthrough
-			case "IFEND":
-				curr := stack.Pop()
-				children := dsa.NewList[yuris.Line]()
-				for stack.Count() > 0 && (curr.Command != "IF" || curr.Visited) && (curr.Command != "ELSE" || curr.Visited) {
-					children.Add(curr)
-					curr = stack.Pop()
-				}
-	
-				children.Reverse()
-
-				start := curr
-				start.Visited = true
-				start.Children = children.Items
-				stack.Push(start)
-	
-				if item.Command == "IFEND" {
-					stack.Push(item)
-				}
-			case "LOOPEND":
-				curr := stack.Pop()
-				children := dsa.NewList[yuris.Line]()
-				for stack.Count() > 0 && (curr.Command != "LOOP" || curr.Visited) {
-					children.Add(curr)
-					curr = stack.Pop()
-				}
-	
-				start := curr
-				start.Visited = true
-				start.Children = children.Items
-				stack.Push(start)
-	
-				if item.Command == "LOOPEND" {
-					stack.Push(item)
-				}
-			default:
-				stack.Push(item)
-			}
-
-			command = iterCommands.Next()
-			commandCount += 1
-		}
+	// Keep supporting the original <script> <YSCom.ycd> <output> form.
+	if len(args) == 3 && strings.HasSuffix(args[0], ".ybn") {
+		return exitCode(runDecompile([]string{"-yscom", args[1], "-o", args[2], args[0]}))
 	}
 
-	lines := dsa.NewList[yuris.Line]()
+	log.Printf("unknown command %q", args[0])
+	printUsage()
+	return exitUsage
+}
 
-	for stack.Count() > 0 {
-		item := stack.Pop()
-		lines.Add(item)
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: yuris-dec <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run yuris-dec <command> --help for the flags of a command.")
+}
 
-	lines.Reverse()
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
 
-	outputPath := args[2]
-	file, err := os.Create(outputPath)
-	if err != nil {
-		log.Fatal("")
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
 	}
-	defer file.Close()
 
-	w := bufio.NewWriter(file)
-	for i := 0; i < lines.Count(); i++ {
-		fmt.Fprintln(w, lines.Items[i].ToString(0))
+	log.Println(err)
+
+	var e *exitError
+	switch {
+	case errors.As(err, &e):
+		return e.code
+	case errors.Is(err, fs.ErrNotExist):
+		return exitNotFound
+	case errors.Is(err, yuris.ErrInvalidFormat):
+		return exitInvalidInput
 	}
 
-	w.Flush()
-}
\ No newline at end of file
+	return exitFailure
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"io"
+	"log"
+	"flag"
+	"sort"
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"path/filepath"
+	"encoding/json"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// Flags shared by the subcommands.
+type options struct {
+	encoding	string
+	key			string
+	format		string
+	output		string
+	outDir		string
+	yscom		string
+	enums		string
+	ysbin		string
+	scriptId	int
+	strict		bool
+	verbose		bool
+	quiet		bool
+
+	args		[]string
+	keyValue	uint32
+	hasKey		bool
+	warnings	int
+}
+
+// A script together with the labels that belong to it.
+type scriptFile struct {
+	Path		string
+	Id			int
+	Script		yuris.Script
+	Labels		[]yuris.Label
+}
+
+func newFlagSet(name string, arguments string, summary string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.encoding, "encoding", "shift-jis", "text encoding of the game files: shift-jis or raw")
+	fs.StringVar(&opts.key, "key", "", "script decryption key in hex, derived from each script if empty")
+	fs.StringVar(&opts.format, "format", "text", "output format: text or json")
+	fs.StringVar(&opts.yscom, "yscom", "", "path to YSCom.ycd, searched next to the scripts if empty")
+	fs.StringVar(&opts.enums, "enums", "", "JSON file with symbolic names of attribute values")
+	fs.StringVar(&opts.ysbin, "ysbin", "", "ysbin directory with ysl.ybn, when reading a script from stdin")
+	fs.IntVar(&opts.scriptId, "id", -1, "script index, required when reading a script from stdin")
+	fs.BoolVar(&opts.strict, "strict", false, "exit with an error if there are warnings")
+	fs.BoolVar(&opts.verbose, "v", false, "print progress information")
+	fs.BoolVar(&opts.quiet, "q", false, "do not print warnings")
+
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "usage: yuris-dec %s [flags] %s\n\n", name, arguments)
+		fmt.Fprintf(out, "%s.\n\nflags:\n", summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// Parses the flags and applies the shared ones.
+func (opts *options) parse(fs *flag.FlagSet, args []string) error {
+	// Allow flags after the positional arguments, until a -- ends them.
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return err
+			}
+			return &exitError{exitUsage, err}
+		}
+
+		parsed := args[:len(args) - len(fs.Args())]
+		args = fs.Args()
+		if isFlagsEnd(fs, parsed) {
+			opts.args = append(opts.args, args...)
+			break
+		}
+
+		if len(args) == 0 {
+			break
+		}
+
+		opts.args = append(opts.args, args[0])
+		args = args[1:]
+	}
+
+	if err := utils.SetEncoding(opts.encoding); err != nil {
+		return usageError("%v", err)
+	}
+
+	switch opts.format {
+	case "text", "json":
+	default:
+		return usageError("unsupported format %q", opts.format)
+	}
+
+	if opts.key != "" {
+		key, err := strconv.ParseUint(strings.TrimPrefix(opts.key, "0x"), 16, 32)
+		if err != nil {
+			return usageError("invalid key %q", opts.key)
+		}
+
+		opts.keyValue = uint32(key)
+		opts.hasKey = true
+	}
+
+	return nil
+}
+
+// Returns true if the parsed arguments end with the -- that ends the flags,
+// rather than with a flag value that happens to be --.
+func isFlagsEnd(fs *flag.FlagSet, parsed []string) bool {
+	n := len(parsed)
+	if n == 0 || parsed[n - 1] != "--" {
+		return false
+	}
+
+	if n == 1 || !strings.HasPrefix(parsed[n - 2], "-") || strings.Contains(parsed[n - 2], "=") {
+		return true
+	}
+
+	f := fs.Lookup(strings.TrimLeft(parsed[n - 2], "-"))
+	if f == nil {
+		return true
+	}
+
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (opts *options) logf(format string, args ...any) {
+	if opts.verbose {
+		log.Printf(format, args...)
+	}
+}
+
+func (opts *options) warn(format string, args ...any) {
+	opts.warnings++
+	if !opts.quiet {
+		log.Printf("warning: " + format, args...)
+	}
+}
+
+// Returns an error if warnings were reported in strict mode.
+func (opts *options) finish() error {
+	if opts.strict && opts.warnings > 0 {
+		return &exitError{exitWarnings, fmt.Errorf("%d warnings in strict mode", opts.warnings)}
+	}
+
+	return nil
+}
+
+// Expands directories into the scripts they contain. A - reads a single
+// script from stdin.
+func expandInputs(args []string) ([]string, error) {
+	inputs := make([]string, 0)
+	for _, arg := range args {
+		if arg == "-" {
+			if len(args) > 1 {
+				return nil, usageError("- cannot be combined with other inputs")
+			}
+			inputs = append(inputs, arg)
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			inputs = append(inputs, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "yst[0-9]*.ybn"))
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(matches)
+		inputs = append(inputs, matches...)
+	}
+
+	return inputs, nil
+}
+
+func scriptId(path string) (int, error) {
+	r, _ := regexp.Compile(".*yst0*(\\d+)\\.ybn$")
+	submatch := r.FindStringSubmatch(filepath.Base(path))
+	if len(submatch) < 2 {
+		return 0, usageError("invalid script file name %s", path)
+	}
+
+	return strconv.Atoi(submatch[1])
+}
+
+func (opts *options) scriptIdOf(path string) (int, error) {
+	if path != "-" {
+		return scriptId(path)
+	}
+
+	if opts.scriptId < 0 {
+		return 0, usageError("-id is required when reading a script from stdin")
+	}
+
+	return opts.scriptId, nil
+}
+
+// Returns the ysbin directory of a script.
+func (opts *options) ysbinOf(path string) string {
+	if path == "-" {
+		return opts.ysbin
+	}
+
+	return filepath.Dir(path)
+}
+
+func (opts *options) readScript(path string) (yuris.Script, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return yuris.Script{}, err
+	}
+
+	return opts.parseScript(data)
+}
+
+// Parses a script with the -key flag, if set.
+func (opts *options) parseScript(data []byte) (yuris.Script, error) {
+	if !opts.hasKey {
+		return yuris.ParseYST(data)
+	}
+
+	return yuris.ParseYSTWithKey(data, opts.keyValue)
+}
+
+// Reads a script and its labels from the ysl.ybn next to it.
+func (opts *options) loadScript(path string) (scriptFile, error) {
+	file := scriptFile{Path: path}
+
+	id, err := opts.scriptIdOf(path)
+	if err != nil {
+		return file, err
+	}
+	file.Id = id
+
+	opts.logf("reading %s", path)
+	file.Script, err = opts.readScript(path)
+	if err != nil {
+		return file, fmt.Errorf("%s: %w", path, err)
+	}
+
+	ysbinPath := opts.ysbinOf(path)
+	if ysbinPath == "" {
+		opts.warn("no -ysbin directory given, decompiling without labels")
+		return file, nil
+	}
+
+	labels, err := readLabels(ysbinPath)
+	if err != nil {
+		return file, err
+	}
+	file.Labels = yuris.ScriptLabels(labels, id)
+
+	return file, nil
+}
+
+func readLabels(ysbinPath string) ([]yuris.Label, error) {
+	labelsPath := filepath.Join(ysbinPath, "ysl.ybn")
+	labels, err := yuris.ReadYSL(labelsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", labelsPath, err)
+	}
+
+	return labels, nil
+}
+
+// Reads YSCom.ycd from the -yscom flag, or from the script directory or its
+// parent.
+func (opts *options) readCompiler(scriptPath string) (yuris.CompilerDefinition, error) {
+	path := opts.yscom
+	if path == "" && opts.ysbinOf(scriptPath) != "" {
+		dir := opts.ysbinOf(scriptPath)
+		for _, candidate := range []string{dir, filepath.Dir(dir)} {
+			candidatePath := filepath.Join(candidate, "YSCom.ycd")
+			if _, err := os.Stat(candidatePath); err == nil {
+				path = candidatePath
+				break
+			}
+		}
+	}
+
+	if path == "" {
+		return yuris.CompilerDefinition{}, usageError("YSCom.ycd not found, use -yscom")
+	}
+
+	opts.logf("reading %s", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return yuris.CompilerDefinition{}, err
+	}
+
+	return opts.parseCompiler(path, data)
+}
+
+// Parses YSCom.ycd and loads the -enums file, if set. The path is only used
+// in errors.
+func (opts *options) parseCompiler(path string, data []byte) (yuris.CompilerDefinition, error) {
+	compiler, err := yuris.ParseYSCom(data)
+	if err != nil {
+		return compiler, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if opts.enums != "" {
+		opts.logf("reading %s", opts.enums)
+		if err := compiler.ReadEnums(opts.enums); err != nil {
+			return compiler, fmt.Errorf("%s: %w", opts.enums, err)
+		}
+	}
+
+	return compiler, nil
+}
+
+// Writes to the given file through a buffered writer. A - writes to stdout.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return outputError(err)
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	w := bufio.NewWriter(out)
+	if err := write(w); err != nil {
+		return outputError(err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return outputError(err)
+	}
+
+	return nil
+}
+
+// Writes to the -o file if set, or to stdout.
+func (opts *options) writeResult(write func(w io.Writer) error) error {
+	if opts.output != "" {
+		return writeOutput(opts.output, write)
+	}
+
+	return writeOutput("-", write)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(v)
+}
+
+// A flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/damianfadri/yuris-decompiler/analysis"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runRoutes(args []string) error {
+	opts := options{}
+	fs := newFlagSet("routes", "<yst00xxx.ybn|ysbin dir>...", "Writes the scenes and choices of the story as a DOT graph, or JSON with -format json", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	choiceNames := fs.String("choices", "SELECT", "comma separated names of the choice commands")
+	symbolsPath := fs.String("symbols", "", "symbol file with variable and label names")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	var symbols *yuris.Symbols
+	if *symbolsPath != "" {
+		symbols, err = yuris.ReadSymbols(*symbolsPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *symbolsPath, err)
+		}
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	graph := analysis.NewRouteGraph(splitNames(*choiceNames), symbols)
+	for _, input := range inputs {
+		file, err := opts.loadScript(input)
+		if err != nil {
+			return err
+		}
+
+		graph.AddScript(file.Id, file.Script, compiler, file.Labels)
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, graph)
+		}
+
+		_, err := io.WriteString(w, graph.ToDot())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
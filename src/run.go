@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/interp"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runRun(args []string) error {
+	opts := options{}
+	fs := newFlagSet("run", "<ysbin dir>", "Plays the script logic in the terminal, printing text and asking for choices", &opts)
+	label := fs.String("label", "", "label to start from, the start of yst00000.ybn if empty")
+	textNames := fs.String("text", "_", "comma separated names of the text commands")
+	choiceNames := fs.String("choices", "SELECT", "comma separated names of the choice commands")
+	choiceVariable := fs.String("choice-var", "", "variable that receives the number of the chosen option")
+	maxSteps := fs.Int("max-steps", 1000000, "stop after this many commands, 0 for no limit")
+	dump := fs.Bool("dump", false, "print the variables when the script ends")
+	symbolsPath := fs.String("symbols", "", "symbol file with variable names")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) != 1 {
+		return usageError("expected a single ysbin directory")
+	}
+
+	config := interp.Config{}
+	config.TextCommands = splitNames(*textNames)
+	config.ChoiceCommands = splitNames(*choiceNames)
+	config.ChoiceVariable = *choiceVariable
+	config.MaxSteps = *maxSteps
+	config.Warn = opts.warn
+	config.Output = os.Stdout
+	config.Choose = promptChoice(bufio.NewReader(os.Stdin), os.Stdout)
+
+	if *symbolsPath != "" {
+		symbols, err := yuris.ReadSymbols(*symbolsPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *symbolsPath, err)
+		}
+		config.Symbols = symbols
+	}
+
+	in, err := opts.loadInterpreter(opts.args[0], config)
+	if err != nil {
+		return err
+	}
+
+	if *label != "" {
+		err = in.Run(*label)
+	} else {
+		err = in.RunAt(interp.Position{})
+	}
+
+	if *dump {
+		for _, name := range in.Vars.Names() {
+			value, _ := in.Vars.Lookup(name)
+			fmt.Printf("%s = %s\n", name, formatValue(value))
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
+
+// Loads every script of a ysbin directory into a new interpreter.
+func (opts *options) loadInterpreter(ysbinPath string, config interp.Config) (*interp.Interpreter, error) {
+	inputs, err := expandInputs([]string{ysbinPath})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, usageError("no scripts found in %s", ysbinPath)
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := readLabels(ysbinPath)
+	if err != nil {
+		return nil, err
+	}
+
+	in := interp.New(compiler, labels, config)
+	for _, input := range inputs {
+		id, err := scriptId(input)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", input, err)
+		}
+
+		in.AddScript(id, script)
+	}
+
+	return in, nil
+}
+
+// Returns a chooser that lists the options and reads the number of the
+// chosen one.
+func promptChoice(r *bufio.Reader, w io.Writer) func(options []string) (int, error) {
+	return func(options []string) (int, error) {
+		for i, option := range options {
+			fmt.Fprintf(w, "  %d) %s\n", i + 1, option)
+		}
+
+		for {
+			fmt.Fprint(w, "> ")
+			line, err := r.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				if err == io.EOF {
+					return 0, errors.New("no choice given before end of input")
+				}
+				return 0, err
+			}
+
+			choice, err := strconv.Atoi(strings.TrimSpace(line))
+			if err == nil && choice >= 1 && choice <= len(options) {
+				return choice - 1, nil
+			}
+
+			fmt.Fprintf(w, "enter a number from 1 to %d\n", len(options))
+		}
+	}
+}
+
+// Formats a value as a literal of its type.
+func formatValue(value yuris.Value) string {
+	if value.Type == yuris.TypeString {
+		return strconv.Quote(value.Str)
+	}
+
+	return value.String()
+}
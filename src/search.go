@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/analysis"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runSearch(args []string) error {
+	opts := options{}
+	fs := newFlagSet("search", "<yst00xxx.ybn|ysbin dir>...", "Finds decompiled commands by name, argument, label and enclosing blocks", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	command := fs.String("command", "", "command name")
+	attribute := fs.String("attr", "", "attribute name")
+	value := fs.String("value", "", "regular expression matching the argument value, without quotes")
+	label := fs.String("label", "", "name of the containing label")
+	insides := stringList{}
+	fs.Var(&insides, "inside", "enclosing block as COMMAND or COMMAND:REGEXP on its condition, e.g. IF:@var1 (repeatable)")
+	symbolsPath := fs.String("symbols", "", "symbol file with variable and label names")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	query := analysis.Query{Command: *command, Attribute: *attribute, Label: *label}
+	if *value != "" {
+		query.Value, err = regexp.Compile(*value)
+		if err != nil {
+			return usageError("invalid -value: %v", err)
+		}
+	}
+
+	for _, inside := range insides {
+		context := analysis.ContextQuery{Command: inside}
+		if colon := strings.Index(inside, ":"); colon >= 0 {
+			context.Command = inside[:colon]
+			context.Condition, err = regexp.Compile(inside[colon + 1:])
+			if err != nil {
+				return usageError("invalid -inside %q: %v", inside, err)
+			}
+		}
+		query.Inside = append(query.Inside, context)
+	}
+
+	decompileOptions := yuris.Options{}
+	if *symbolsPath != "" {
+		decompileOptions.Symbols, err = yuris.ReadSymbols(*symbolsPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *symbolsPath, err)
+		}
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	matches := make([]analysis.SearchMatch, 0)
+	for _, input := range inputs {
+		file, err := opts.loadScript(input)
+		if err != nil {
+			return err
+		}
+
+		lines, _ := yuris.Decompile(file.Script, compiler, file.Labels, decompileOptions)
+		matches = append(matches, analysis.Search(lines, file.Id, query)...)
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, matches)
+		}
+
+		for _, match := range matches {
+			label := match.Label
+			if label == "" {
+				label = "-"
+			}
+			fmt.Fprintf(w, "yst%05d:%d\t%s\t%s\n", match.Script, match.Index, label, match.Line)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
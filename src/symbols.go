@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runSymbols(args []string) error {
+	opts := options{}
+	fs := newFlagSet("symbols", "<ysbin dir>...", "Generates a symbol file skeleton with every variable and label of a game", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	merge := fs.String("merge", "", "existing symbol file whose names and comments are kept")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) == 0 {
+		return usageError("missing ysbin directory")
+	}
+
+	symbols := yuris.NewSymbols()
+	if *merge != "" {
+		existing, err := yuris.ReadSymbols(*merge)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *merge, err)
+		}
+		symbols = existing
+	}
+
+	for _, dir := range opts.args {
+		inputs, err := expandInputs([]string{dir})
+		if err != nil {
+			return err
+		}
+
+		for _, input := range inputs {
+			opts.logf("reading %s", input)
+			script, err := opts.readScript(input)
+			if err != nil {
+				return fmt.Errorf("%s: %w", input, err)
+			}
+
+			for _, attribute := range script.Attributes {
+				for _, token := range yuris.Tokenize(attribute.Bytes) {
+					if !token.IsVariable() {
+						continue
+					}
+
+					key := yuris.VariableKey(token.Variable())
+					if _, ok := symbols.Variables[key]; !ok {
+						symbols.Variables[key] = yuris.Symbol{}
+					}
+				}
+			}
+		}
+
+		labels, err := readLabels(filepath.Clean(dir))
+		if err != nil {
+			opts.warn("%v, skipping labels", err)
+			continue
+		}
+
+		for _, label := range labels {
+			if _, ok := symbols.Labels[label.Name]; !ok {
+				symbols.Labels[label.Name] = yuris.Symbol{}
+			}
+		}
+	}
+
+	err := opts.writeResult(func(w io.Writer) error {
+		return writeJSON(w, symbols)
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
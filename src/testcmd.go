@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/damianfadri/yuris-decompiler/interp"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runTest(args []string) error {
+	opts := options{}
+	fs := newFlagSet("test", "<ysbin dir> <file.test>...", "Plays scripted choices and checks variables and reached labels", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	textNames := fs.String("text", "_", "comma separated names of the text commands")
+	choiceNames := fs.String("choices", "SELECT", "comma separated names of the choice commands")
+	choiceVariable := fs.String("choice-var", "", "variable that receives the number of the chosen option")
+	maxSteps := fs.Int("max-steps", 1000000, "fail a test after this many commands, 0 for no limit")
+	symbolsPath := fs.String("symbols", "", "symbol file with variable names")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(opts.args) < 2 {
+		return usageError("expected a ysbin directory and at least one test file")
+	}
+
+	config := interp.Config{}
+	config.TextCommands = splitNames(*textNames)
+	config.ChoiceCommands = splitNames(*choiceNames)
+	config.ChoiceVariable = *choiceVariable
+	config.MaxSteps = *maxSteps
+	config.Warn = opts.warn
+	if opts.verbose {
+		config.Output = os.Stderr
+	}
+
+	if *symbolsPath != "" {
+		symbols, err := yuris.ReadSymbols(*symbolsPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *symbolsPath, err)
+		}
+		config.Symbols = symbols
+	}
+
+	in, err := opts.loadInterpreter(opts.args[0], config)
+	if err != nil {
+		return err
+	}
+
+	results := make([]interp.ScenarioResult, 0)
+	for _, path := range opts.args[1:] {
+		scenarios, err := interp.ReadScenarios(path)
+		if err != nil {
+			return &exitError{exitInvalidInput, fmt.Errorf("%s: %w", path, err)}
+		}
+
+		for _, scenario := range scenarios {
+			opts.logf("running %s: %s", path, scenario.Name)
+			result := in.RunScenario(scenario)
+			result.Name = path + ": " + result.Name
+			results = append(results, result)
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if len(result.Failures) > 0 {
+			failed++
+		}
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, results)
+		}
+
+		for _, result := range results {
+			if len(result.Failures) == 0 {
+				fmt.Fprintf(w, "PASS %s\n", result.Name)
+				continue
+			}
+
+			fmt.Fprintf(w, "FAIL %s\n", result.Name)
+			for _, failure := range result.Failures {
+				fmt.Fprintf(w, "  %s\n", failure)
+			}
+		}
+
+		fmt.Fprintf(w, "%d passed, %d failed\n", len(results) - failed, failed)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(results))
+	}
+
+	return opts.finish()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type textEntry struct {
+	Script		int		`json:"script"`
+	Index		int		`json:"index"`
+	Command		string	`json:"command"`
+	Attribute	string	`json:"attribute"`
+	Text		string	`json:"text"`
+}
+
+func runExtractText(args []string) error {
+	opts := options{}
+	fs := newFlagSet("extract-text", "<yst00xxx.ybn|ysbin dir>...", "Extracts the string literals of scripts", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	commandNames := fs.String("commands", "", "comma separated command names to extract from, all if empty")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	filter := make(map[string]bool)
+	for _, name := range strings.Split(*commandNames, ",") {
+		if name != "" {
+			filter[name] = true
+		}
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	entries := make([]textEntry, 0)
+	for _, input := range inputs {
+		id, err := opts.scriptIdOf(input)
+		if err != nil {
+			return err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		for i, command := range script.Commands {
+			name := compiler.Commands[command.Id]
+			if len(filter) > 0 && !filter[name] {
+				continue
+			}
+
+			for _, attribute := range script.CommandAttributes(command) {
+				for _, token := range yuris.Tokenize(attribute.Bytes) {
+					if token.Opcode != 0x4d {
+						continue
+					}
+
+					entry := textEntry{}
+					entry.Script = id
+					entry.Index = i
+					entry.Command = name
+					entry.Attribute = compiler.Definition(command.Id, attribute.Id).Name
+					entry.Text = token.Text()
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, entries)
+		}
+
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", entry.Script, entry.Index, entry.Command, entry.Attribute, entry.Text)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
@@ -1,11 +1,14 @@
 package utils
 
 import (
+	"fmt"
 	"math"
 	"bytes"
+	"strings"
 	"io/ioutil"
 	"encoding/binary"
 
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/encoding/japanese"
 )
@@ -80,10 +83,37 @@ func (r *BinaryReader) ReadDouble() float64 {
 	return float64(math.Float64frombits(tmp))
 }
 
+// Encoding of strings in YU-RIS files. Strings are converted to UTF-8 when
+// read, unless the encoding is nil.
+var textEncoding encoding.Encoding = japanese.ShiftJIS
+
+// Sets the encoding of strings by name: shift-jis, utf-8 or raw.
+func SetEncoding(name string) error {
+	switch strings.ToLower(name) {
+	case "shift-jis", "shift_jis", "sjis", "cp932":
+		textEncoding = japanese.ShiftJIS
+	case "utf-8", "utf8", "raw":
+		textEncoding = nil
+	default:
+		return fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	return nil
+}
+
+// Decodes a string with the encoding used to read strings.
+func DecodeString(data []byte) string {
+	return toShiftJIS(data)
+}
+
 func toShiftJIS(data []byte) string {
+	if textEncoding == nil {
+		return string(data)
+	}
+
 	reader := bytes.NewReader(data)
-	encoder := japanese.ShiftJIS.NewEncoder()
-	ret, _ := ioutil.ReadAll(transform.NewReader(reader, encoder))
+	decoder := textEncoding.NewDecoder()
+	ret, _ := ioutil.ReadAll(transform.NewReader(reader, decoder))
 
 	return string(ret)
 }
\ No newline at end of file
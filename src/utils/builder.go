@@ -16,7 +16,7 @@ func NewStringBuilder() *StringBuilder {
 
 func (b *StringBuilder) Append(s string) {
 	sb := b.builder
-	fmt.Fprintf(sb, s)
+	fmt.Fprint(sb, s)
 }
 
 func (b *StringBuilder) ToString() string {
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"strings"
+)
+
+// Escapes backslashes and double quotes for a Graphviz DOT string.
+func EscapeDot(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return strings.ReplaceAll(s, "\"", "\\\"")
+}
+
+// Returns the string as a quoted Graphviz DOT id.
+func QuoteDot(s string) string {
+	return "\"" + EscapeDot(s) + "\""
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"encoding/binary"
+
+	"golang.org/x/text/transform"
+)
+
+type BinaryWriter struct {
+	Bytes			[]byte
+}
+
+func NewBinaryWriter() *BinaryWriter {
+	return &BinaryWriter{}
+}
+
+func (w *BinaryWriter) WriteBytes(bs ...byte) {
+	w.Bytes = append(w.Bytes, bs...)
+}
+
+func (w *BinaryWriter) WriteInt16(n int16) {
+	bs := make([]byte, 2)
+	binary.LittleEndian.PutUint16(bs, uint16(n))
+	w.WriteBytes(bs...)
+}
+
+func (w *BinaryWriter) WriteInt32(n int) {
+	bs := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bs, uint32(n))
+	w.WriteBytes(bs...)
+}
+
+// Encodes a string with the encoding used to read strings.
+func EncodeString(s string) ([]byte, error) {
+	if textEncoding == nil {
+		return []byte(s), nil
+	}
+
+	reader := bytes.NewReader([]byte(s))
+	encoder := textEncoding.NewEncoder()
+	return ioutil.ReadAll(transform.NewReader(reader, encoder))
+}
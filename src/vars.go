@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+type variableUsage struct {
+	Name		string	`json:"name"`
+	Prefix		string	`json:"prefix"`
+	Id			int16	`json:"id"`
+	References	int		`json:"references"`
+	Scripts		[]int	`json:"scripts"`
+}
+
+func runVars(args []string) error {
+	opts := options{}
+	fs := newFlagSet("vars", "<yst00xxx.ybn|ysbin dir>...", "Lists the variables referenced by scripts", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	usages := make(map[string]*variableUsage)
+	for _, input := range inputs {
+		id, err := opts.scriptIdOf(input)
+		if err != nil {
+			return err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		for _, attribute := range script.Attributes {
+			for _, token := range yuris.Tokenize(attribute.Bytes) {
+				if !token.IsVariable() {
+					continue
+				}
+
+				prefix, varId := token.Variable()
+				name := fmt.Sprintf("%svar%x", prefix, varId)
+				usage, ok := usages[name]
+				if !ok {
+					usage = &variableUsage{Name: name, Prefix: prefix, Id: varId}
+					usages[name] = usage
+				}
+
+				usage.References++
+				if n := len(usage.Scripts); n == 0 || usage.Scripts[n - 1] != id {
+					usage.Scripts = append(usage.Scripts, id)
+				}
+			}
+		}
+	}
+
+	sorted := make([]*variableUsage, 0, len(usages))
+	for _, usage := range usages {
+		sorted = append(sorted, usage)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Prefix != sorted[j].Prefix {
+			return sorted[i].Prefix < sorted[j].Prefix
+		}
+		return sorted[i].Id < sorted[j].Id
+	})
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, sorted)
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "VARIABLE\tREFERENCES\tSCRIPTS")
+		for _, usage := range sorted {
+			fmt.Fprintf(tw, "%s\t%d\t%v\n", usage.Name, usage.References, usage.Scripts)
+		}
+
+		return tw.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/damianfadri/yuris-decompiler/analysis"
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func runXref(args []string) error {
+	opts := options{}
+	fs := newFlagSet("xref", "<yst00xxx.ybn|ysbin dir>...", "Lists where each variable is declared, written and read", &opts)
+	fs.StringVar(&opts.output, "o", "", "output file, stdout if empty")
+	symbolsPath := fs.String("symbols", "", "symbol file with variable names")
+	variable := fs.String("var", "", "only show this variable, by default or symbol name")
+
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	inputs, err := expandInputs(opts.args)
+	if err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return usageError("missing yst00xxx.ybn path")
+	}
+
+	var symbols *yuris.Symbols
+	if *symbolsPath != "" {
+		symbols, err = yuris.ReadSymbols(*symbolsPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *symbolsPath, err)
+		}
+	}
+
+	compiler, err := opts.readCompiler(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	xref := analysis.NewXref(symbols)
+	for _, input := range inputs {
+		id, err := opts.scriptIdOf(input)
+		if err != nil {
+			return err
+		}
+
+		opts.logf("reading %s", input)
+		script, err := opts.readScript(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		xref.AddScript(id, script, compiler)
+	}
+
+	if *variable != "" {
+		for key, refs := range xref.Variables {
+			if refs.Key != *variable && refs.Name != *variable {
+				delete(xref.Variables, key)
+			}
+		}
+	}
+
+	err = opts.writeResult(func(w io.Writer) error {
+		if opts.format == "json" {
+			return writeJSON(w, xref.Sorted())
+		}
+
+		_, err := io.WriteString(w, xref.ToText())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return opts.finish()
+}
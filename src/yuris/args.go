@@ -0,0 +1,100 @@
+package yuris
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Renders the value of a command attribute using the type of its
+// definition. Returns false if the argument should be omitted.
+func renderArgument(compiler CompilerDefinition, commandName string, def AttributeDefinition, attr *Attribute, options Options, warn func(string)) (string, bool) {
+	value := attr.DecompileWith(options.Symbols)
+	resultType := attr.ResultType()
+
+	switch def.Type {
+	case AttrTypeInt, AttrTypeDouble, AttrTypeFlag:
+		if resultType == TypeString {
+			warn(fmt.Sprintf("%s[%s] expects a number but got %s", commandName, def.Name, value))
+		}
+	case AttrTypeString:
+		if resultType == TypeInt || resultType == TypeDouble {
+			warn(fmt.Sprintf("%s[%s] expects a string but got %s", commandName, def.Name, value))
+		}
+	}
+
+	if !attr.IsLiteral() {
+		return value, true
+	}
+
+	switch def.Type {
+	case AttrTypeInt, AttrTypeDouble, AttrTypeFlag:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			break
+		}
+
+		// The value of a declaration is kept, since it is printed even
+		// when it is 0.
+		if options.OmitDefaults && number == int(def.Default) && !IsDeclaration(commandName) {
+			return value, false
+		}
+
+		enum, ok := compiler.Enums[EnumKey{commandName, def.Name}]
+		if !ok {
+			break
+		}
+
+		if name, ok := enum[number]; ok {
+			return name, true
+		}
+
+		if def.Type == AttrTypeFlag {
+			if names, ok := flagNames(enum, number); ok {
+				return names, true
+			}
+		}
+	case AttrTypeString:
+		if resultType == TypeString {
+			return quote(value), true
+		}
+	}
+
+	return value, true
+}
+
+// Returns the string literal surrounded with double quotes.
+func quote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+		return value
+	}
+
+	return "\"" + strings.Trim(value, "\"") + "\""
+}
+
+// Decomposes a flag value into the names of its bits, joined with |.
+func flagNames(enum map[int]string, value int) (string, bool) {
+	bits := make([]int, 0, len(enum))
+	for bit := range enum {
+		if bit > 0 && bit & (bit - 1) == 0 {
+			bits = append(bits, bit)
+		}
+	}
+	sort.Ints(bits)
+
+	names := make([]string, 0)
+	remaining := value
+	for _, bit := range bits {
+		if remaining & bit != 0 {
+			names = append(names, enum[bit])
+			remaining &^= bit
+		}
+	}
+
+	if remaining != 0 || len(names) == 0 {
+		return "", false
+	}
+
+	return strings.Join(names, "|"), true
+}
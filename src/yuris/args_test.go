@@ -0,0 +1,67 @@
+package yuris_test
+
+import (
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestFlagNames(t *testing.T) {
+	enum := map[int]string{0: "NONE", 1: "LOOP", 2: "FADE", 4: "WAIT", 6: "BOTH"}
+
+	tests := []struct {
+		value		int
+		want		string
+		ok			bool
+	}{
+		{1, "LOOP", true},
+		{3, "LOOP|FADE", true},
+		{7, "LOOP|FADE|WAIT", true},
+		{8, "", false},
+		{9, "", false},
+		{0, "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := yuris.FlagNames(enum, test.value)
+		if got != test.want || ok != test.ok {
+			t.Errorf("FlagNames(%d) = %q, %v, want %q, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRenderArgumentEnums(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("SE", yuristest.Token(0x42, 3), yuristest.Token(0x42, 2)),
+		yuristest.Command("SE", yuristest.Token(0x42, 9), yuristest.Token(0x42, 0)),
+	)
+	compiler.Definitions[0][0] = yuris.AttributeDefinition{Name: "LOOP", Type: yuris.AttrTypeFlag}
+	compiler.Definitions[0][1] = yuris.AttributeDefinition{Name: "MODE", Type: yuris.AttrTypeInt}
+	compiler.SetEnum("SE", "LOOP", map[int]string{1: "REPEAT", 2: "FADE"})
+	compiler.SetEnum("SE", "MODE", map[int]string{2: "STREAM"})
+
+	lines, _ := yuris.Decompile(script, compiler, nil, yuris.Options{})
+
+	want := "SE[LOOP=REPEAT|FADE MODE=STREAM]\n\nSE[LOOP=9 MODE=0]\n\n"
+	if got := yuris.DefaultFormat().Lines(lines); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOmitDefaultsKeepsDeclarations(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("INT", yuristest.Variable(1), yuristest.Token(0x42, 0)),
+		yuristest.Command("CG", yuristest.Token(0x42, 0), yuristest.Token(0x42, 5)),
+	)
+	compiler.Definitions[0][1] = yuris.AttributeDefinition{Name: "VALUE", Type: yuris.AttrTypeInt}
+	compiler.Definitions[1][0] = yuris.AttributeDefinition{Name: "X", Type: yuris.AttrTypeInt}
+	compiler.Definitions[1][1] = yuris.AttributeDefinition{Name: "Y", Type: yuris.AttrTypeInt}
+
+	lines, _ := yuris.Decompile(script, compiler, nil, yuris.Options{OmitDefaults: true})
+
+	want := "INT[@var1]\n\nCG[Y=5]\n\n"
+	if got := yuris.DefaultFormat().Lines(lines); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
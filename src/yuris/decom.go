@@ -5,11 +5,30 @@ import (
 )
 
 type Line struct {
-	Command		string
-	Arguments	[]string
-	Names		[]string
-	Children	[]Line
-	Visited		bool
+	// Index of the command, or the offset of a label.
+	Index		int			`json:"index"`
+	Command		string		`json:"command"`
+	Arguments	[]string	`json:"arguments"`
+	Names		[]string	`json:"names"`
+	Children	[]Line		`json:"children,omitempty"`
+	Visited		bool		`json:"-"`
+	Comment		string		`json:"comment,omitempty"`
+
+	// Set on IF statements, in source order. Else is nil when the chain
+	// has no final ELSE without a condition.
+	Branches	[]Branch	`json:"branches,omitempty"`
+	Else		*Branch		`json:"else,omitempty"`
+	// Comment of the IFEND of an IF statement.
+	EndComment	string		`json:"endComment,omitempty"`
+}
+
+type Branch struct {
+	// Index of the IF or ELSE command.
+	Index		int		`json:"index"`
+	Name		string	`json:"name"`
+	Condition	string	`json:"condition"`
+	Body		[]Line	`json:"body"`
+	Comment		string	`json:"comment,omitempty"`
 }
 
 func getIndent(count int) string {
@@ -22,105 +41,9 @@ func getIndent(count int) string {
 }
 
 func (item *Line) ToStringSingle(indent int) string {
-	sb := utils.NewStringBuilder()
-
-	sb.Append(getIndent(indent))
-
-	switch item.Command {
-	case "LET":
-		sb.Append(item.Arguments[0])
-		sb.Append(" ")
-		sb.Append(item.Arguments[1])
-		sb.Append(" ")
-		sb.Append(item.Arguments[2])
-	case "LABEL":
-		sb.Append("#=")
-		sb.Append(item.Arguments[0])
-	case "IF":
-		sb.Append("IF")
-		sb.Append("[")
-		sb.Append(item.Arguments[0])
-		sb.Append("]")
-	case "ELSE":
-		sb.Append("ELSE")
-		sb.Append("[")
-		if (len(item.Arguments) > 0) {
-			sb.Append(item.Arguments[0])
-		}
-		sb.Append("]")
-	case "LOOP":
-		sb.Append("LOOP")
-		sb.Append("[")
-		if (item.Arguments[0] != "255") {
-			sb.Append(item.Names[0])
-			sb.Append(" = ")
-			sb.Append(item.Arguments[0])
-		}
-		sb.Append("]")
-	case "IFEND":
-		fallthrough
-	case "LOOPEND":
-		fallthrough
-	case "LOOPBREAK":
-		fallthrough
-	case "LOOPCONTINUE":
-		fallthrough
-	case "END":
-		sb.Append(item.Command)
-		sb.Append("[]")
-	case "S_INT":
-		fallthrough
-	case "S_STR":
-		fallthrough
-	case "INT":
-		fallthrough
-	case "STR":
-		sb.Append(item.Command)
-		sb.Append("[")
-		sb.Append(item.Arguments[0])
-		if (item.Arguments[1] != "0") {
-			sb.Append(" = ")
-			sb.Append(item.Arguments[1])
-		}
-		sb.Append("]")
-	default:
-		sb.Append(item.Command)
-		sb.Append("[")
-		for i := 0; i < len(item.Arguments); i++ {
-			sb.Append(item.Names[i])
-			sb.Append("=")
-			sb.Append(item.Arguments[i])
-
-			if (i + 1 < len(item.Arguments)) {
-				sb.Append(" ")
-			}
-		}
-		sb.Append("]")
-	}
-
-	sb.Append("\n")
-	return sb.ToString()
+	return DefaultFormat().single(item, getIndent(indent))
 }
 
 func (item *Line) ToString(indent int) string {
-	if len(item.Children) == 0 {
-		return item.ToStringSingle(indent)
-	}
-
-	sb := utils.NewStringBuilder()
-	ind := getIndent(indent)
-
-	sb.Append(item.ToStringSingle(indent))
-	sb.Append(ind)
-	sb.Append("{")
-	sb.Append("\n")
-	for i := 0; i < len(item.Children); i++ {
-		child := item.Children[i]
-		sb.Append(child.ToString(indent + 2))
-	}
-	sb.Append(ind)
-	sb.Append("}")
-	sb.Append("\n")
-
-	return sb.ToString()
-}
\ No newline at end of file
+	return DefaultFormat().line(item, getIndent(indent))
+}
@@ -0,0 +1,21 @@
+package yuris
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when a file does not match the expected YU-RIS format.
+var ErrInvalidFormat = errors.New("invalid format")
+
+func formatError(message string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidFormat, message)
+}
+
+// Converts a panic caused by reading past the end of truncated data into an
+// invalid format error.
+func recoverFormat(file string, err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: truncated %s data (%v)", ErrInvalidFormat, file, r)
+	}
+}
@@ -0,0 +1,432 @@
+package yuris
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+)
+
+var ErrEvaluation = errors.New("cannot evaluate expression")
+
+// A runtime value of an expression.
+type Value struct {
+	Type		ValueType
+	Int			int64
+	Double		float64
+	Str			string
+}
+
+// Variable storage used to evaluate expressions.
+type Environment interface {
+	// Returns the value of a variable, or false if it is unknown. Indices
+	// are empty unless an array element is read.
+	Get(prefix string, id int16, indices []int64) (Value, bool)
+}
+
+func IntValue(n int64) Value {
+	return Value{Type: TypeInt, Int: n}
+}
+
+func DoubleValue(n float64) Value {
+	return Value{Type: TypeDouble, Double: n}
+}
+
+func StringValue(s string) Value {
+	return Value{Type: TypeString, Str: s}
+}
+
+func boolValue(b bool) Value {
+	if b {
+		return IntValue(1)
+	}
+	return IntValue(0)
+}
+
+// Returns the zero value of a variable with the given prefix.
+func DefaultValue(prefix string) Value {
+	if prefix == "$" {
+		return StringValue("")
+	}
+	return IntValue(0)
+}
+
+// Returns the value as it is printed to the player.
+func (v Value) String() string {
+	switch v.Type {
+	case TypeDouble:
+		return strconv.FormatFloat(v.Double, 'f', -1, 64)
+	case TypeString:
+		return v.Str
+	}
+
+	return strconv.FormatInt(v.Int, 10)
+}
+
+// Returns true if the value is a non-zero number or a non-empty string.
+func (v Value) IsTrue() bool {
+	switch v.Type {
+	case TypeDouble:
+		return v.Double != 0
+	case TypeString:
+		return v.Str != ""
+	}
+
+	return v.Int != 0
+}
+
+// Converts the value as @() does. Strings that are not numbers become 0.
+func (v Value) ToNumber() Value {
+	switch v.Type {
+	case TypeString:
+		s := strings.TrimSpace(v.Str)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return IntValue(n)
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return DoubleValue(n)
+		}
+		return IntValue(0)
+	}
+
+	return v
+}
+
+// Converts the value as $() does.
+func (v Value) ToString() Value {
+	return StringValue(v.String())
+}
+
+func (v Value) float() float64 {
+	if v.Type == TypeDouble {
+		return v.Double
+	}
+	return float64(v.Int)
+}
+
+// Evaluates the attribute value with the variables of the environment.
+func (attr *Attribute) Evaluate(env Environment) (Value, error) {
+	return Evaluate(Tokenize(attr.Bytes), env)
+}
+
+// Evaluates an RPN expression. Reading a variable that the environment does
+// not know, or any variable if env is nil, fails with ErrEvaluation.
+func Evaluate(tokens []Token, env Environment) (Value, error) {
+	type index struct {
+		prefix		string
+		id			int16
+		depth		int
+	}
+
+	stack := dsa.NewStack[Value]()
+	indexes := dsa.NewStack[index]()
+
+	pop := func() (Value, error) {
+		if stack.Count() == 0 || (indexes.Count() > 0 && stack.Count() <= indexes.Peek().depth) {
+			return Value{}, fmt.Errorf("%w: stack underflow", ErrEvaluation)
+		}
+		return stack.Pop(), nil
+	}
+
+	get := func(prefix string, id int16, indices []int64) (Value, error) {
+		if env != nil {
+			if value, ok := env.Get(prefix, id, indices); ok {
+				return value, nil
+			}
+		}
+		return Value{}, fmt.Errorf("%w: unknown variable %s", ErrEvaluation, VariableKey(prefix, id))
+	}
+
+	for _, token := range tokens {
+		br := utils.NewBinaryReader(token.Operand)
+
+		switch token.Opcode {
+		case 0x42:	// int8
+			stack.Push(IntValue(int64(int8(br.ReadByte()))))
+		case 0x57:	// int16
+			stack.Push(IntValue(int64(br.ReadInt16())))
+		case 0x49:	// int32
+			stack.Push(IntValue(int64(int32(br.ReadInt32()))))
+		case 0x4c:	// int64
+			stack.Push(IntValue(br.ReadInt64()))
+		case 0x46:	// double
+			stack.Push(DoubleValue(br.ReadDouble()))
+		case 0x4d:	// string
+			stack.Push(StringValue(unquote(token.Text())))
+		case 0x48:	// variable
+			prefix, id := token.Variable()
+			value, err := get(prefix, id, nil)
+			if err != nil {
+				return Value{}, err
+			}
+			stack.Push(value)
+		case 0x76:	// array var
+			prefix, id := token.Variable()
+			return Value{}, fmt.Errorf("%w: whole array %s() has no value", ErrEvaluation, VariableKey(prefix, id))
+		case 0x56:	// start var index
+			prefix, id := token.Variable()
+			indexes.Push(index{prefix, id, stack.Count()})
+		case 0x2c:	// array separator
+		case 0x29:	// end var index
+			if indexes.Count() == 0 {
+				return Value{}, fmt.Errorf("%w: unmatched index end", ErrEvaluation)
+			}
+
+			open := indexes.Pop()
+			indices := make([]int64, stack.Count() - open.depth)
+			for i := len(indices) - 1; i >= 0; i-- {
+				indices[i] = stack.Pop().ToNumber().Int
+			}
+
+			value, err := get(open.prefix, open.id, indices)
+			if err != nil {
+				return Value{}, err
+			}
+			stack.Push(value)
+		case 0x52:	// change sign
+			value, err := pop()
+			if err != nil {
+				return Value{}, err
+			}
+
+			value, err = Operate('-', IntValue(0), value)
+			if err != nil {
+				return Value{}, err
+			}
+			stack.Push(value)
+		case 0x69:	// to number
+			value, err := pop()
+			if err != nil {
+				return Value{}, err
+			}
+			stack.Push(value.ToNumber())
+		case 0x73:	// to string
+			value, err := pop()
+			if err != nil {
+				return Value{}, err
+			}
+			stack.Push(value.ToString())
+		default:
+			second, err := pop()
+			if err != nil {
+				return Value{}, err
+			}
+
+			first, err := pop()
+			if err != nil {
+				return Value{}, err
+			}
+
+			value, err := Operate(token.Opcode, first, second)
+			if err != nil {
+				return Value{}, err
+			}
+			stack.Push(value)
+		}
+	}
+
+	if stack.Count() != 1 || indexes.Count() > 0 {
+		return Value{}, fmt.Errorf("%w: malformed expression", ErrEvaluation)
+	}
+
+	return stack.Pop(), nil
+}
+
+// Applies the binary operator with the given opcode.
+func Operate(opcode byte, first Value, second Value) (Value, error) {
+	isString := first.Type == TypeString || second.Type == TypeString
+	isDouble := first.Type == TypeDouble || second.Type == TypeDouble
+
+	switch opcode {
+	case 0x26:	// logical and
+		return boolValue(first.IsTrue() && second.IsTrue()), nil
+	case 0x7c:	// logical or
+		return boolValue(first.IsTrue() || second.IsTrue()), nil
+	case 0x3d, 0x21, 0x3c, 0x3e, 0x53, 0x5a:	// comparisons
+		return boolValue(compare(opcode, first, second)), nil
+	}
+
+	if isString {
+		if opcode == 0x2b {
+			return StringValue(first.String() + second.String()), nil
+		}
+		return Value{}, fmt.Errorf("%w: operator %#02x on a string", ErrEvaluation, opcode)
+	}
+
+	switch opcode {
+	case 0x41, 0x4f, 0x5e:
+		// How the engine converts doubles for binary operators is not known.
+		if isDouble {
+			return Value{}, fmt.Errorf("%w: binary operator %#02x on a double", ErrEvaluation, opcode)
+		}
+	}
+
+	switch opcode {
+	case 0x41:	// binary and
+		return IntValue(first.Int & second.Int), nil
+	case 0x4f:	// binary or
+		return IntValue(first.Int | second.Int), nil
+	case 0x5e:	// binary xor
+		return IntValue(first.Int ^ second.Int), nil
+	}
+
+	if isDouble {
+		a := first.float()
+		b := second.float()
+
+		switch opcode {
+		case 0x2b:
+			return DoubleValue(a + b), nil
+		case 0x2d:
+			return DoubleValue(a - b), nil
+		case 0x2a:
+			return DoubleValue(a * b), nil
+		case 0x2f:
+			return DoubleValue(a / b), nil
+		case 0x25:
+			return DoubleValue(math.Mod(a, b)), nil
+		}
+	} else {
+		a := first.Int
+		b := second.Int
+
+		switch opcode {
+		case 0x2b:
+			return IntValue(a + b), nil
+		case 0x2d:
+			return IntValue(a - b), nil
+		case 0x2a:
+			return IntValue(a * b), nil
+		case 0x2f, 0x25:
+			if b == 0 {
+				return Value{}, fmt.Errorf("%w: division by zero", ErrEvaluation)
+			}
+			if opcode == 0x2f {
+				return IntValue(a / b), nil
+			}
+			return IntValue(a % b), nil
+		}
+	}
+
+	return Value{}, fmt.Errorf("%w: unknown operator %#02x", ErrEvaluation, opcode)
+}
+
+func compare(opcode byte, first Value, second Value) bool {
+	order := 0
+	if first.Type == TypeString || second.Type == TypeString {
+		order = strings.Compare(first.String(), second.String())
+	} else if a, b := first.float(), second.float(); first.Type == TypeDouble || second.Type == TypeDouble {
+		if a < b {
+			order = -1
+		} else if a > b {
+			order = 1
+		}
+	} else if first.Int < second.Int {
+		order = -1
+	} else if first.Int > second.Int {
+		order = 1
+	}
+
+	switch opcode {
+	case 0x3d:
+		return order == 0
+	case 0x21:
+		return order != 0
+	case 0x3c:
+		return order < 0
+	case 0x3e:
+		return order > 0
+	case 0x53:
+		return order <= 0
+	}
+
+	return order >= 0
+}
+
+// Opcodes of the binary operators of compound assignments, by LET operator
+// code.
+var assignmentOpcodes = map[byte]byte{
+	1: 0x2b,
+	2: 0x2d,
+	3: 0x2a,
+	4: 0x2f,
+	5: 0x25,
+	6: 0x41,
+	7: 0x4f,
+	8: 0x5e,
+}
+
+// Returns the value a LET with the given operator code assigns.
+func Assign(code byte, current Value, value Value) (Value, error) {
+	if code == 0 {
+		return value, nil
+	}
+
+	opcode, ok := assignmentOpcodes[code]
+	if !ok {
+		return Value{}, fmt.Errorf("%w: unknown assignment operator 0x%02x", ErrEvaluation, code)
+	}
+
+	return Operate(opcode, current, value)
+}
+
+// Removes the quotes around a string literal.
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, "\"") && strings.HasSuffix(s, "\"") {
+		return s[1:len(s) - 1]
+	}
+	return s
+}
+
+// Resolves the variable assigned by a LET or declaration attribute, with the
+// values of its indices.
+func EvaluateTarget(tokens []Token, env Environment) (string, int16, []int64, error) {
+	if len(tokens) == 0 || !tokens[0].IsVariable() {
+		return "", 0, nil, fmt.Errorf("%w: assignment target is not a variable", ErrEvaluation)
+	}
+
+	prefix, id := tokens[0].Variable()
+	if tokens[0].Opcode != 0x56 {
+		return prefix, id, nil, nil
+	}
+
+	if tokens[len(tokens) - 1].Opcode != 0x29 {
+		return "", 0, nil, fmt.Errorf("%w: unmatched index start", ErrEvaluation)
+	}
+
+	indices := make([]int64, 0)
+	start := 1
+	depth := 0
+	for i := 1; i < len(tokens); i++ {
+		switch tokens[i].Opcode {
+		case 0x56:
+			depth++
+			continue
+		case 0x29:
+			if depth > 0 {
+				depth--
+				continue
+			}
+		case 0x2c:
+			if depth > 0 {
+				continue
+			}
+		default:
+			continue
+		}
+
+		value, err := Evaluate(tokens[start:i], env)
+		if err != nil {
+			return "", 0, nil, err
+		}
+
+		indices = append(indices, value.ToNumber().Int)
+		start = i + 1
+	}
+
+	return prefix, id, indices, nil
+}
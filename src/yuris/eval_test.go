@@ -0,0 +1,100 @@
+package yuris
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOperate(t *testing.T) {
+	tests := []struct {
+		name		string
+		opcode		byte
+		first		Value
+		second		Value
+		want		Value
+	}{
+		{"add", 0x2b, IntValue(2), IntValue(3), IntValue(5)},
+		{"subtract", 0x2d, IntValue(2), IntValue(3), IntValue(-1)},
+		{"multiply", 0x2a, IntValue(-4), IntValue(3), IntValue(-12)},
+		{"divide truncates", 0x2f, IntValue(-7), IntValue(2), IntValue(-3)},
+		{"modulo", 0x25, IntValue(7), IntValue(3), IntValue(1)},
+		{"double", 0x2f, DoubleValue(1), IntValue(4), DoubleValue(0.25)},
+		{"double modulo", 0x25, DoubleValue(7.5), IntValue(2), DoubleValue(1.5)},
+		{"binary and", 0x41, IntValue(6), IntValue(3), IntValue(2)},
+		{"binary or", 0x4f, IntValue(6), IntValue(3), IntValue(7)},
+		{"binary xor", 0x5e, IntValue(6), IntValue(3), IntValue(5)},
+		{"logical and", 0x26, IntValue(2), StringValue(""), IntValue(0)},
+		{"logical or", 0x7c, IntValue(0), StringValue("a"), IntValue(1)},
+		{"concatenate", 0x2b, StringValue("a"), IntValue(1), StringValue("a1")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Operate(test.opcode, test.first, test.second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("Operate(%#02x, %v, %v) = %+v, want %+v", test.opcode, test.first, test.second, got, test.want)
+			}
+		})
+	}
+}
+
+func TestOperateErrors(t *testing.T) {
+	tests := []struct {
+		name		string
+		opcode		byte
+		first		Value
+		second		Value
+		want		string
+	}{
+		{"division by zero", 0x2f, IntValue(1), IntValue(0), "division by zero"},
+		{"modulo by zero", 0x25, IntValue(1), IntValue(0), "division by zero"},
+		{"string subtract", 0x2d, StringValue("a"), IntValue(1), "operator 0x2d on a string"},
+		{"unknown operator", 0x3f, IntValue(1), IntValue(1), "unknown operator 0x3f"},
+		{"double and", 0x41, DoubleValue(1.5), IntValue(1), "binary operator 0x41 on a double"},
+		{"double or", 0x4f, IntValue(1), DoubleValue(2), "binary operator 0x4f on a double"},
+		{"double xor", 0x5e, DoubleValue(1), DoubleValue(2), "binary operator 0x5e on a double"},
+	}
+
+	for _, test := range tests {
+		_, err := Operate(test.opcode, test.first, test.second)
+		if !errors.Is(err, ErrEvaluation) {
+			t.Errorf("%s: Operate() = %v, want ErrEvaluation", test.name, err)
+			continue
+		}
+
+		if !strings.HasSuffix(err.Error(), ": " + test.want) {
+			t.Errorf("%s: Operate() = %v, want %s", test.name, err, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name		string
+		first		Value
+		second		Value
+		// Results of ==, !=, <, >, <= and >=.
+		want		[6]bool
+	}{
+		{"equal ints", IntValue(1), IntValue(1), [6]bool{true, false, false, false, true, true}},
+		{"smaller int", IntValue(-1), IntValue(1), [6]bool{false, true, true, false, true, false}},
+		{"int and double", IntValue(1), DoubleValue(1.5), [6]bool{false, true, true, false, true, false}},
+		{"equal int and double", DoubleValue(2), IntValue(2), [6]bool{true, false, false, false, true, true}},
+		{"strings", StringValue("b"), StringValue("a"), [6]bool{false, true, false, true, false, true}},
+		{"string and int", StringValue("10"), IntValue(10), [6]bool{true, false, false, false, true, true}},
+		{"strings compare as text", StringValue("10"), StringValue("9"), [6]bool{false, true, true, false, true, false}},
+	}
+
+	opcodes := []byte{0x3d, 0x21, 0x3c, 0x3e, 0x53, 0x5a}
+	for _, test := range tests {
+		for i, opcode := range opcodes {
+			if got := compare(opcode, test.first, test.second); got != test.want[i] {
+				t.Errorf("%s: compare(0x%02x) = %v, want %v", test.name, opcode, got, test.want[i])
+			}
+		}
+	}
+}
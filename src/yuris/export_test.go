@@ -0,0 +1,8 @@
+package yuris
+
+// Internal functions used by the tests of package yuris_test.
+var (
+	FlagNames		= flagNames
+	LiteralToken	= literalToken
+	EncodeTokens	= encodeTokens
+)
@@ -0,0 +1,179 @@
+package yuris
+
+import (
+	"github.com/damianfadri/yuris-decompiler/utils"
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+)
+
+type ValueType byte
+
+const (
+	TypeUnknown ValueType = iota
+	TypeInt
+	TypeDouble
+	TypeString
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeDouble:
+		return "double"
+	case TypeString:
+		return "string"
+	}
+
+	return "unknown"
+}
+
+// A single RPN instruction of an attribute value.
+type Token struct {
+	Opcode		byte
+	Operand		[]byte
+}
+
+// Splits an attribute value into its RPN instructions.
+func Tokenize(data []byte) []Token {
+	tokens := dsa.NewList[Token]()
+	br := utils.NewBinaryReader(data)
+	for br.Position + 3 <= len(br.Bytes) {
+		token := Token{}
+		token.Opcode = br.ReadByte()
+		length := int(br.ReadInt16())
+		if length < 0 || br.Position + length > len(br.Bytes) {
+			length = len(br.Bytes) - br.Position
+		}
+		token.Operand = br.ReadBytes(length)
+
+		tokens.Add(token)
+	}
+
+	return tokens.Items
+}
+
+// Returns true if the token reads a variable, either directly or as an
+// array.
+func (t Token) IsVariable() bool {
+	return t.Opcode == 0x48 || t.Opcode == 0x56 || t.Opcode == 0x76
+}
+
+// Returns the prefix and id of a variable token.
+func (t Token) Variable() (string, int16) {
+	if len(t.Operand) < 3 {
+		return "", 0
+	}
+
+	br := utils.NewBinaryReader(t.Operand)
+	prefix := br.ReadString(1)
+	id := br.ReadInt16()
+
+	return prefix, id
+}
+
+// Returns the text of a string literal token.
+func (t Token) Text() string {
+	br := utils.NewBinaryReader(t.Operand)
+	return br.ReadString(len(t.Operand))
+}
+
+// Returns true if the attribute value is a single constant.
+func (attr *Attribute) IsLiteral() bool {
+	tokens := Tokenize(attr.Bytes)
+	if len(tokens) != 1 {
+		return false
+	}
+
+	switch tokens[0].Opcode {
+	case 0x42, 0x46, 0x49, 0x4c, 0x4d, 0x57:
+		return true
+	}
+
+	return false
+}
+
+// Infers the type of the value of the attribute expression.
+func (attr *Attribute) ResultType() ValueType {
+	const marker = ValueType(0xff)
+
+	stack := dsa.NewStack[ValueType]()
+	pop := func() ValueType {
+		if stack.Count() == 0 {
+			return TypeUnknown
+		}
+		return stack.Pop()
+	}
+
+	for _, token := range Tokenize(attr.Bytes) {
+		switch token.Opcode {
+		case 0x42, 0x49, 0x4c, 0x57:	// integers
+			stack.Push(TypeInt)
+		case 0x46:	// double
+			stack.Push(TypeDouble)
+		case 0x4d:	// string
+			stack.Push(TypeString)
+		case 0x48, 0x76:	// variable, array var
+			prefix, _ := token.Variable()
+			stack.Push(variableType(prefix))
+		case 0x56:	// start var index
+			prefix, _ := token.Variable()
+			stack.Push(variableType(prefix))
+			stack.Push(marker)
+		case 0x29:	// end var index
+			for stack.Count() > 0 && stack.Peek() != marker {
+				stack.Pop()
+			}
+			pop()
+		case 0x2c:	// array separator
+		case 0x52:	// change sign
+			stack.Push(pop())
+		case 0x69:	// to number
+			pop()
+			stack.Push(TypeInt)
+		case 0x73:	// to string
+			pop()
+			stack.Push(TypeString)
+		case 0x2b:	// add
+			second := pop()
+			first := pop()
+			stack.Push(arithmeticType(first, second, true))
+		case 0x25, 0x2a, 0x2d, 0x2f:	// modulo, multiply, subtract, divide
+			second := pop()
+			first := pop()
+			stack.Push(arithmeticType(first, second, false))
+		default:	// comparisons, logical and bitwise operators
+			pop()
+			pop()
+			stack.Push(TypeInt)
+		}
+	}
+
+	return pop()
+}
+
+func variableType(prefix string) ValueType {
+	switch prefix {
+	case "@":
+		return TypeInt
+	case "$":
+		return TypeString
+	}
+
+	return TypeUnknown
+}
+
+func arithmeticType(first ValueType, second ValueType, isAdd bool) ValueType {
+	if isAdd && (first == TypeString || second == TypeString) {
+		return TypeString
+	}
+
+	if first == TypeDouble || second == TypeDouble {
+		return TypeDouble
+	}
+
+	if first == TypeUnknown || second == TypeUnknown {
+		return TypeUnknown
+	}
+
+	return TypeInt
+}
@@ -0,0 +1,189 @@
+package yuris
+
+import (
+	"math"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+)
+
+// A value on the folding stack, produced by the output tokens from start.
+type foldEntry struct {
+	start		int
+	constant	bool
+	marker		bool
+	value		Value
+}
+
+// Returns a copy of the attribute with constant sub-expressions, such as
+// -(5) or 2 * 3, replaced by their value.
+func (attr *Attribute) FoldConstants() Attribute {
+	folded := *attr
+	folded.Bytes = encodeTokens(FoldConstants(Tokenize(attr.Bytes)))
+
+	return folded
+}
+
+// Replaces the constant sub-expressions of an RPN expression by their value.
+// Sub-expressions that fail to evaluate are kept as they are.
+func FoldConstants(tokens []Token) []Token {
+	output := dsa.NewList[Token]()
+	stack := dsa.NewStack[foldEntry]()
+
+	// Replaces the tokens from start with the literal if the value can be
+	// encoded.
+	fold := func(start int, value Value, token Token) {
+		literal, ok := literalToken(value)
+		if !ok {
+			output.Add(token)
+			stack.Push(foldEntry{start: start})
+			return
+		}
+
+		output.Items = output.Items[:start]
+		output.Add(literal)
+		stack.Push(foldEntry{start, true, false, value})
+	}
+
+	for _, token := range tokens {
+		start := output.Count()
+
+		switch token.Opcode {
+		case 0x42, 0x57, 0x49, 0x4c, 0x46, 0x4d:	// literals
+			output.Add(token)
+
+			value, err := Evaluate([]Token{token}, nil)
+			stack.Push(foldEntry{start, err == nil, false, value})
+		case 0x48, 0x76:	// variable, array var
+			output.Add(token)
+			stack.Push(foldEntry{start: start})
+		case 0x56:	// start var index
+			output.Add(token)
+			stack.Push(foldEntry{start: start, marker: true})
+		case 0x2c:	// array separator
+			output.Add(token)
+		case 0x29:	// end var index
+			output.Add(token)
+			for stack.Count() > 0 && !stack.Peek().marker {
+				stack.Pop()
+			}
+			if stack.Count() > 0 {
+				start = stack.Pop().start
+			}
+			stack.Push(foldEntry{start: start})
+		case 0x52, 0x69, 0x73:	// change sign, to number, to string
+			if stack.Count() == 0 || stack.Peek().marker {
+				return tokens
+			}
+
+			operand := stack.Pop()
+
+			var value Value
+			var err error = ErrEvaluation
+			if operand.constant {
+				value, err = evaluateUnary(token, operand.value)
+			}
+
+			if err != nil {
+				output.Add(token)
+				stack.Push(foldEntry{start: operand.start})
+				continue
+			}
+
+			fold(operand.start, value, token)
+		default:	// binary operators
+			if stack.Count() < 2 {
+				return tokens
+			}
+
+			second := stack.Pop()
+			first := stack.Pop()
+			if first.marker || second.marker {
+				return tokens
+			}
+
+			var value Value
+			var err error = ErrEvaluation
+			if first.constant && second.constant {
+				value, err = Operate(token.Opcode, first.value, second.value)
+			}
+
+			if err != nil {
+				output.Add(token)
+				stack.Push(foldEntry{start: first.start})
+				continue
+			}
+
+			fold(first.start, value, token)
+		}
+	}
+
+	return output.Items
+}
+
+func evaluateUnary(token Token, operand Value) (Value, error) {
+	switch token.Opcode {
+	case 0x69:
+		return operand.ToNumber(), nil
+	case 0x73:
+		return operand.ToString(), nil
+	}
+
+	return Operate(0x2d, IntValue(0), operand)
+}
+
+// Returns the literal token of a value, using the smallest integer opcode
+// that keeps its sign.
+func literalToken(value Value) (Token, bool) {
+	switch value.Type {
+	case TypeString:
+		bs, err := utils.EncodeString("\"" + value.Str + "\"")
+		if err != nil {
+			return Token{}, false
+		}
+		return Token{0x4d, bs}, true
+	case TypeDouble:
+		w := utils.NewBinaryWriter()
+		w.WriteBytes(uint64Bytes(math.Float64bits(value.Double))...)
+		return Token{0x46, w.Bytes}, true
+	}
+
+	n := value.Int
+	w := utils.NewBinaryWriter()
+	switch {
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return Token{0x42, []byte{byte(n)}}, true
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		w.WriteInt16(int16(n))
+		return Token{0x57, w.Bytes}, true
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		w.WriteInt32(int(n))
+		return Token{0x49, w.Bytes}, true
+	}
+
+	return Token{0x4c, int64Operand(n)}, true
+}
+
+func int64Operand(n int64) []byte {
+	return uint64Bytes(uint64(n))
+}
+
+func uint64Bytes(n uint64) []byte {
+	bs := make([]byte, 8)
+	for i := range bs {
+		bs[i] = byte(n >> (8 * i))
+	}
+	return bs
+}
+
+// Encodes RPN instructions back into an attribute value.
+func encodeTokens(tokens []Token) []byte {
+	w := utils.NewBinaryWriter()
+	for _, token := range tokens {
+		w.WriteBytes(token.Opcode)
+		w.WriteInt16(int16(len(token.Operand)))
+		w.WriteBytes(token.Operand...)
+	}
+
+	return w.Bytes
+}
@@ -0,0 +1,128 @@
+package yuris_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestLiteralToken(t *testing.T) {
+	tests := []struct {
+		name		string
+		value		yuris.Value
+		want		[]byte
+	}{
+		{"zero", yuris.IntValue(0), yuristest.Token(0x42, 0x00)},
+		{"negative int8", yuris.IntValue(-2), yuristest.Token(0x42, 0xfe)},
+		{"int8 max", yuris.IntValue(math.MaxInt8), yuristest.Token(0x42, 0x7f)},
+		{"int16", yuris.IntValue(math.MaxInt8 + 1), yuristest.Token(0x57, 0x80, 0x00)},
+		{"negative int16", yuris.IntValue(math.MinInt8 - 1), yuristest.Token(0x57, 0x7f, 0xff)},
+		{"int32", yuris.IntValue(math.MaxInt16 + 1), yuristest.Token(0x49, 0x00, 0x80, 0x00, 0x00)},
+		{"negative int32", yuris.IntValue(math.MinInt32), yuristest.Token(0x49, 0x00, 0x00, 0x00, 0x80)},
+		{"int64", yuris.IntValue(math.MaxInt32 + 1), yuristest.Token(0x4c, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00)},
+		{"double", yuris.DoubleValue(1), yuristest.Token(0x46, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f)},
+		{"string", yuris.StringValue("ab"), yuristest.Token(0x4d, []byte("\"ab\"")...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			literal, ok := yuris.LiteralToken(test.value)
+			if !ok {
+				t.Fatalf("LiteralToken(%+v) failed", test.value)
+			}
+
+			if got := yuris.EncodeTokens([]yuris.Token{literal}); !bytes.Equal(got, test.want) {
+				t.Errorf("EncodeTokens() = %x, want %x", got, test.want)
+			}
+
+			value, err := yuris.Evaluate([]yuris.Token{literal}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != test.value {
+				t.Errorf("literal evaluates to %+v, want %+v", value, test.value)
+			}
+		})
+	}
+}
+
+func TestEncodeTokens(t *testing.T) {
+	tests := []struct {
+		name		string
+		data		[]byte
+	}{
+		{"empty", nil},
+		{"literal", yuristest.Token(0x57, 0x05, 0x00)},
+		{"expression", yuristest.Expr(yuristest.Token(0x48, '@', 0x01, 0x00), yuristest.Token(0x42, 0x02), yuristest.Token(0x2b))},
+		{"array element", yuristest.Expr(yuristest.Token(0x56, '$', 0x02, 0x00), yuristest.Token(0x42, 0x01), yuristest.Token(0x29))},
+	}
+
+	for _, test := range tests {
+		if got := yuris.EncodeTokens(yuris.Tokenize(test.data)); !bytes.Equal(got, test.data) {
+			t.Errorf("%s: EncodeTokens(Tokenize(%x)) = %x", test.name, test.data, got)
+		}
+	}
+}
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		name		string
+		data		[]byte
+		want		[]byte
+	}{
+		{
+			name:	"negative number",
+			data:	yuristest.Expr(yuristest.Token(0x57, 0x05, 0x00), yuristest.Token(0x52)),
+			want:	yuristest.Token(0x42, 0xfb),
+		},
+		{
+			name:	"product",
+			data:	yuristest.Expr(yuristest.Token(0x42, 0x02), yuristest.Token(0x42, 0x03), yuristest.Token(0x2a)),
+			want:	yuristest.Token(0x42, 0x06),
+		},
+		{
+			name:	"grows to int16",
+			data:	yuristest.Expr(yuristest.Token(0x42, 0x7f), yuristest.Token(0x42, 0x02), yuristest.Token(0x2a)),
+			want:	yuristest.Token(0x57, 0xfe, 0x00),
+		},
+		{
+			name:	"variable operand is kept",
+			data:	yuristest.Expr(yuristest.Token(0x48, '@', 0x01, 0x00), yuristest.Token(0x42, 0x02), yuristest.Token(0x42, 0x03), yuristest.Token(0x2b), yuristest.Token(0x2a)),
+			want:	yuristest.Expr(yuristest.Token(0x48, '@', 0x01, 0x00), yuristest.Token(0x42, 0x05), yuristest.Token(0x2a)),
+		},
+		{
+			name:	"division by zero is kept",
+			data:	yuristest.Expr(yuristest.Token(0x42, 0x01), yuristest.Token(0x42, 0x00), yuristest.Token(0x2f)),
+			want:	yuristest.Expr(yuristest.Token(0x42, 0x01), yuristest.Token(0x42, 0x00), yuristest.Token(0x2f)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := yuris.EncodeTokens(yuris.FoldConstants(yuris.Tokenize(test.data))); !bytes.Equal(got, test.want) {
+				t.Errorf("FoldConstants() = %x, want %x", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecompileSignedIntegers(t *testing.T) {
+	tests := []struct {
+		data		[]byte
+		want		string
+	}{
+		{yuristest.Token(0x42, 0xfe), "-2"},
+		{yuristest.Token(0x42, 0x7f), "127"},
+		{yuristest.Token(0x49, 0xff, 0xff, 0xff, 0xff), "-1"},
+	}
+
+	for _, test := range tests {
+		attr := yuris.Attribute{Bytes: test.data}
+		if got := attr.Decompile(); got != test.want {
+			t.Errorf("Decompile(%x) = %q, want %q", test.data, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package yuris
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+)
+
+type BraceStyle byte
+
+const (
+	// Braces on their own line, at the indentation of the block start.
+	BracesOwnLine BraceStyle = iota
+	// Opening brace at the end of the block start line.
+	BracesSameLine
+	// No braces, blocks are only indented.
+	BracesNone
+)
+
+// Layout of the decompiled source.
+type Format struct {
+	// Indentation of one nesting level.
+	Indent			string
+	Braces			BraceStyle
+	// Put spaces around the = between attribute names and values.
+	SpacedEquals	bool
+	// Put each argument on its own line if a line is longer than this.
+	// Zero never wraps.
+	MaxWidth		int
+	// Line ending, "\n" or "\r\n".
+	Newline			string
+}
+
+// Returns the format of Line.ToString.
+func DefaultFormat() Format {
+	return Format{Indent: "  ", Braces: BracesOwnLine, Newline: "\n"}
+}
+
+// Formats the top-level lines of a script, separated by blank lines.
+func (f Format) Lines(lines []Line) string {
+	sb := utils.NewStringBuilder()
+	for i := range lines {
+		sb.Append(f.line(&lines[i], ""))
+		sb.Append(f.Newline)
+	}
+
+	return sb.ToString()
+}
+
+// Formats a line and the blocks it contains.
+func (f Format) Line(item Line) string {
+	return f.line(&item, "")
+}
+
+func (f Format) line(item *Line, prefix string) string {
+	if item.Command == "IF" && item.Branches != nil {
+		return f.ifStatement(item, prefix)
+	}
+
+	if len(item.Children) == 0 {
+		return f.single(item, prefix)
+	}
+
+	return f.block(f.single(item, prefix), item.Children, prefix)
+}
+
+func (f Format) ifStatement(item *Line, prefix string) string {
+	sb := utils.NewStringBuilder()
+
+	for i, branch := range item.Branches {
+		if i == 0 && branch.Index < 0 {
+			// The IF is outside of the decompiled lines.
+			for j := range branch.Body {
+				sb.Append(f.line(&branch.Body[j], prefix))
+			}
+			continue
+		}
+
+		head := prefix + "ELSE[" + branch.Condition + "]" + comment(branch.Comment) + f.Newline
+		if i == 0 {
+			head = prefix + "IF[" + branch.Condition + "]" + comment(branch.Comment) + f.Newline
+		}
+		sb.Append(f.block(head, branch.Body, prefix))
+	}
+
+	if item.Else != nil {
+		sb.Append(f.block(prefix + "ELSE[]" + comment(item.Else.Comment) + f.Newline, item.Else.Body, prefix))
+	}
+
+	sb.Append(prefix)
+	sb.Append("IFEND[]")
+	sb.Append(comment(item.EndComment))
+	sb.Append(f.Newline)
+
+	return sb.ToString()
+}
+
+// Formats the head line of a block followed by its body.
+func (f Format) block(head string, lines []Line, prefix string) string {
+	sb := utils.NewStringBuilder()
+
+	switch f.Braces {
+	case BracesSameLine:
+		sb.Append(strings.TrimSuffix(head, f.Newline))
+		sb.Append(" {")
+		sb.Append(f.Newline)
+	case BracesNone:
+		sb.Append(head)
+	default:
+		sb.Append(head)
+		sb.Append(prefix)
+		sb.Append("{")
+		sb.Append(f.Newline)
+	}
+
+	for i := range lines {
+		sb.Append(f.line(&lines[i], prefix + f.Indent))
+	}
+
+	if f.Braces != BracesNone {
+		sb.Append(prefix)
+		sb.Append("}")
+		sb.Append(f.Newline)
+	}
+
+	return sb.ToString()
+}
+
+// Formats a line without the blocks it contains.
+func (f Format) single(item *Line, prefix string) string {
+	sb := utils.NewStringBuilder()
+
+	sb.Append(prefix)
+
+	switch item.Command {
+	case "LET":
+		sb.Append(item.Arguments[0])
+		sb.Append(" ")
+		sb.Append(item.Arguments[1])
+		sb.Append(" ")
+		sb.Append(item.Arguments[2])
+	case "LABEL":
+		sb.Append("#=")
+		sb.Append(item.Arguments[0])
+	case "IF":
+		sb.Append("IF")
+		sb.Append("[")
+		if (len(item.Arguments) > 0) {
+			sb.Append(item.Arguments[0])
+		}
+		sb.Append("]")
+	case "ELSE":
+		sb.Append("ELSE")
+		sb.Append("[")
+		if (len(item.Arguments) > 0) {
+			sb.Append(item.Arguments[0])
+		}
+		sb.Append("]")
+	case "IFEND":
+		fallthrough
+	case "LOOPEND":
+		fallthrough
+	case "LOOPBREAK":
+		fallthrough
+	case "LOOPCONTINUE":
+		fallthrough
+	case "END":
+		sb.Append(item.Command)
+		sb.Append("[]")
+	case "S_INT":
+		fallthrough
+	case "S_STR":
+		fallthrough
+	case "INT":
+		fallthrough
+	case "STR":
+		sb.Append(item.Command)
+		sb.Append("[")
+		sb.Append(item.Arguments[0])
+		if (len(item.Arguments) > 1 && item.Arguments[1] != "0") {
+			sb.Append(" = ")
+			sb.Append(item.Arguments[1])
+		}
+		sb.Append("]")
+	default:
+		sb.Append(f.arguments(item, prefix))
+	}
+
+	sb.Append(comment(item.Comment))
+	sb.Append(f.Newline)
+	return sb.ToString()
+}
+
+// Returns the comment at the end of a line, if any.
+func comment(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	return " // " + text
+}
+
+// Formats a command with its arguments, wrapping them if the line is too
+// long.
+func (f Format) arguments(item *Line, prefix string) string {
+	equals := "="
+	if f.SpacedEquals {
+		equals = " = "
+	}
+
+	arguments := make([]string, len(item.Arguments))
+	for i := range item.Arguments {
+		arguments[i] = item.Names[i] + equals + item.Arguments[i]
+	}
+
+	line := item.Command + "[" + strings.Join(arguments, " ") + "]"
+	if f.MaxWidth <= 0 || len(arguments) < 2 || utf8.RuneCountInString(prefix + line) <= f.MaxWidth {
+		return line
+	}
+
+	sb := utils.NewStringBuilder()
+	sb.Append(item.Command)
+	sb.Append("[")
+	sb.Append(f.Newline)
+	for _, argument := range arguments {
+		sb.Append(prefix)
+		sb.Append(f.Indent)
+		sb.Append(argument)
+		sb.Append(f.Newline)
+	}
+	sb.Append(prefix)
+	sb.Append("]")
+
+	return sb.ToString()
+}
@@ -0,0 +1,94 @@
+package yuris_test
+
+import (
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+func TestFormat(t *testing.T) {
+	lines := []yuris.Line{
+		{
+			Command:	"LOOP",
+			Names:		[]string{"SET"},
+			Arguments:	[]string{"3"},
+			Children:	[]yuris.Line{
+				{Command: "CG", Names: []string{"X", "Y"}, Arguments: []string{"1", "2"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name		string
+		format		func(f *yuris.Format)
+		want		string
+	}{
+		{
+			name:	"default",
+			format:	func(f *yuris.Format) {},
+			want:	"LOOP[SET=3]\n{\n  CG[X=1 Y=2]\n}\n\n",
+		},
+		{
+			name:	"braces on the same line",
+			format:	func(f *yuris.Format) { f.Braces = yuris.BracesSameLine },
+			want:	"LOOP[SET=3] {\n  CG[X=1 Y=2]\n}\n\n",
+		},
+		{
+			name:	"no braces",
+			format:	func(f *yuris.Format) { f.Braces = yuris.BracesNone },
+			want:	"LOOP[SET=3]\n  CG[X=1 Y=2]\n\n",
+		},
+		{
+			name:	"tab indent",
+			format:	func(f *yuris.Format) { f.Indent = "\t" },
+			want:	"LOOP[SET=3]\n{\n\tCG[X=1 Y=2]\n}\n\n",
+		},
+		{
+			name:	"spaced equals",
+			format:	func(f *yuris.Format) { f.SpacedEquals = true },
+			want:	"LOOP[SET = 3]\n{\n  CG[X = 1 Y = 2]\n}\n\n",
+		},
+		{
+			name:	"wrap",
+			format:	func(f *yuris.Format) { f.MaxWidth = 12 },
+			want:	"LOOP[SET=3]\n{\n  CG[\n    X=1\n    Y=2\n  ]\n}\n\n",
+		},
+		{
+			name:	"wrap at the width",
+			format:	func(f *yuris.Format) { f.MaxWidth = 13 },
+			want:	"LOOP[SET=3]\n{\n  CG[X=1 Y=2]\n}\n\n",
+		},
+		{
+			name:	"crlf",
+			format:	func(f *yuris.Format) { f.Newline = "\r\n" },
+			want:	"LOOP[SET=3]\r\n{\r\n  CG[X=1 Y=2]\r\n}\r\n\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := yuris.DefaultFormat()
+			test.format(&f)
+			if got := f.Lines(lines); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatWrapCountsCharacters(t *testing.T) {
+	lines := []yuris.Line{
+		{Command: "CG", Names: []string{"A", "B"}, Arguments: []string{`"あいう"`, `"えお"`}},
+	}
+
+	f := yuris.DefaultFormat()
+	f.MaxWidth = 18
+	if got, want := f.Lines(lines), "CG[A=\"あいう\" B=\"えお\"]\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	f.MaxWidth = 17
+	if got, want := f.Lines(lines), "CG[\n  A=\"あいう\"\n  B=\"えお\"\n]\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package yuris
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"io/ioutil"
+	"encoding/json"
+)
+
+type Symbol struct {
+	Name		string		`json:"name"`
+	Comment		string		`json:"comment"`
+}
+
+// User supplied names for variables and labels. Variables are keyed by
+// their default name, such as @var1a, and labels by their ysl.ybn name.
+type Symbols struct {
+	Variables	map[string]Symbol		`json:"variables"`
+	Labels		map[string]Symbol		`json:"labels"`
+}
+
+func NewSymbols() *Symbols {
+	return &Symbols{
+		Variables:	make(map[string]Symbol),
+		Labels:		make(map[string]Symbol),
+	}
+}
+
+func ReadSymbols(path string) (*Symbols, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := NewSymbols()
+	if err := json.Unmarshal(data, symbols); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	return symbols, nil
+}
+
+// Returns the default name of a variable.
+func VariableKey(prefix string, id int16) string {
+	return fmt.Sprintf("%svar%x", prefix, id)
+}
+
+// Parses a default variable name such as @var1f into its prefix and id.
+func ParseVariableKey(key string) (string, int16, bool) {
+	if len(key) < 5 || key[1:4] != "var" {
+		return "", 0, false
+	}
+
+	id, err := strconv.ParseInt(key[4:], 16, 16)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return key[:1], int16(id), true
+}
+
+// Finds a variable by its default name or by its symbol name.
+func (s *Symbols) FindVariable(name string) (string, int16, bool) {
+	if prefix, id, ok := ParseVariableKey(name); ok {
+		return prefix, id, true
+	}
+
+	if s == nil {
+		return "", 0, false
+	}
+
+	for key, symbol := range s.Variables {
+		prefix, id, ok := ParseVariableKey(key)
+		if ok && symbol.Name != "" && s.VariableName(prefix, id) == name {
+			return prefix, id, true
+		}
+	}
+
+	return "", 0, false
+}
+
+func (s *Symbols) Variable(prefix string, id int16) (Symbol, bool) {
+	if s == nil {
+		return Symbol{}, false
+	}
+
+	symbol, ok := s.Variables[VariableKey(prefix, id)]
+	return symbol, ok && symbol.Name != ""
+}
+
+// Returns the name of a variable, keeping the prefix of the variable if the
+// symbol omits it.
+func (s *Symbols) VariableName(prefix string, id int16) string {
+	symbol, ok := s.Variable(prefix, id)
+	if !ok {
+		return VariableKey(prefix, id)
+	}
+
+	if strings.HasPrefix(symbol.Name, prefix) {
+		return symbol.Name
+	}
+
+	return prefix + symbol.Name
+}
+
+func (s *Symbols) Label(name string) (Symbol, bool) {
+	if s == nil {
+		return Symbol{}, false
+	}
+
+	symbol, ok := s.Labels[name]
+	return symbol, ok && symbol.Name != ""
+}
+
+// Returns the alias of a label, or the label name if it has none.
+func (s *Symbols) LabelName(name string) string {
+	if symbol, ok := s.Label(name); ok {
+		return symbol.Name
+	}
+
+	return name
+}
@@ -0,0 +1,611 @@
+package yuris
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+)
+
+type Options struct {
+	// Omit arguments equal to the default value of their attribute.
+	OmitDefaults		bool
+
+	// Print the ysl.ybn id of each label as a comment.
+	LabelIds			bool
+
+	// Names and comments for variables and labels.
+	Symbols				*Symbols
+
+	// Replace constant sub-expressions by their value.
+	FoldConstants		bool
+
+	// Comment every line with the index, id and attribute descriptors of
+	// its command, and every label with its ysl.ybn id.
+	SourceComments		bool
+}
+
+// A problem found while decompiling the command at Index.
+type Warning struct {
+	Index		int
+	Message		string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("command %d: %s", w.Index, w.Message)
+}
+
+// Rebuilds the block structure of a script. The labels must belong to the
+// given script, as returned by ScriptLabels.
+func Decompile(script Script, compiler CompilerDefinition, labels []Label, options Options) ([]Line, []Warning) {
+	return decompile(script, compiler, labels, options, 0)
+}
+
+// Decompiles the commands in [start, end) only. Blocks that are open at
+// the start or the end of the range are reported as warnings.
+func DecompileRange(script Script, compiler CompilerDefinition, labels []Label, options Options, start int, end int) ([]Line, []Warning) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(script.Commands) {
+		end = len(script.Commands)
+	}
+	if end < start {
+		end = start
+	}
+
+	regionLabels := dsa.NewList[Label]()
+	for _, label := range labels {
+		if label.Offset >= start && (label.Offset < end || end == len(script.Commands)) {
+			regionLabels.Add(label)
+		}
+	}
+
+	region := script
+	region.Commands = script.Commands[start:end]
+
+	lines, warnings := decompile(region, compiler, regionLabels.Items, options, start)
+	return lines, append(openBlockWarnings(script, compiler, start, end), warnings...)
+}
+
+// Decompiles the commands from the label up to the next label of the same
+// script. The label is looked up in the whole label table, so a label of
+// another script or a name shared by several labels is an error.
+func DecompileLabel(script Script, compiler CompilerDefinition, labels []Label, options Options, scriptIndex int, name string) ([]Line, []Warning, error) {
+	matches := dsa.NewList[Label]()
+	for _, label := range labels {
+		if label.Name == name {
+			matches.Add(label)
+		}
+	}
+
+	switch matches.Count() {
+	case 0:
+		return nil, nil, fmt.Errorf("label %s not found", name)
+	case 1:
+	default:
+		locations := make([]string, 0, matches.Count())
+		for _, label := range matches.Items {
+			locations = append(locations, fmt.Sprintf("yst%05d:%d", label.ScriptIndex, label.Offset))
+		}
+		return nil, nil, fmt.Errorf("label %s is ambiguous, it is defined at %s", name, strings.Join(locations, ", "))
+	}
+
+	target := matches.Items[0]
+	if int(target.ScriptIndex) != scriptIndex {
+		return nil, nil, fmt.Errorf("label %s is in yst%05d, not yst%05d", name, target.ScriptIndex, scriptIndex)
+	}
+
+	scriptLabels := ScriptLabels(labels, scriptIndex)
+	start := target.Offset
+	end := len(script.Commands)
+	for _, label := range scriptLabels {
+		if label.Offset > start && label.Offset < end {
+			end = label.Offset
+		}
+	}
+
+	lines, warnings := DecompileRange(script, compiler, scriptLabels, options, start, end)
+	return lines, warnings, nil
+}
+
+// Returns warnings for the IF, LOOP and WORD blocks that are open where the
+// range starts, or still open where it ends.
+func openBlockWarnings(script Script, compiler CompilerDefinition, start int, end int) []Warning {
+	warnings := dsa.NewList[Warning]()
+	open := dsa.NewStack[string]()
+	startsInside := false
+
+	for i := 0; i < end; i++ {
+		if i == start && open.Count() > 0 {
+			warnings.Add(Warning{start, fmt.Sprintf("range starts inside an open %s block", open.Peek())})
+			open = dsa.NewStack[string]()
+			startsInside = true
+		}
+
+		switch compiler.Commands[script.Commands[i].Id] {
+		case "IF":
+			open.Push("IF")
+		case "LOOP":
+			open.Push("LOOP")
+		case "WORD":
+			open.Push("WORD")
+		case "IFEND", "LOOPEND", "RETURNCODE":
+			if open.Count() > 0 {
+				open.Pop()
+			} else if i >= start && !startsInside {
+				warnings.Add(Warning{i, "range starts inside an open block"})
+				startsInside = true
+			}
+		}
+	}
+
+	if open.Count() > 0 && end > start {
+		warnings.Add(Warning{end - 1, fmt.Sprintf("range ends inside an open %s block", open.Peek())})
+	}
+
+	return warnings.Items
+}
+
+func decompile(script Script, compiler CompilerDefinition, labels []Label, options Options, origin int) ([]Line, []Warning) {
+	if options.FoldConstants {
+		attributes := make([]Attribute, len(script.Attributes))
+		for i := range script.Attributes {
+			attributes[i] = script.Attributes[i].FoldConstants()
+		}
+		script.Attributes = attributes
+	}
+
+	warnings := dsa.NewList[Warning]()
+	iterCommands := dsa.NewIterator[Command](script.Commands)
+	attributes := script.Attributes
+	if len(script.Commands) > 0 && script.Commands[0].AttributeIndex <= len(attributes) {
+		attributes = attributes[script.Commands[0].AttributeIndex:]
+	}
+	iterAttributes := dsa.NewIterator[Attribute](attributes)
+	iterLabels := dsa.NewIterator[Label](labels)
+	
+	stack := dsa.NewStack[Line]()
+	callTargets := findCallTargets(script, compiler)
+
+	label := iterLabels.Next()
+	command := iterCommands.Next()
+	commandCount := origin
+	openLoops := 0
+	for command != nil || label != nil {
+		item := Line{Index: commandCount}
+
+		// Labels are emitted as flat markers, and only become blocks once
+		// a RETURN closes them. Labels past the last command are emitted
+		// at the end of the script.
+		if label != nil && (command == nil || commandCount >= label.Offset) {
+			args := dsa.NewList[string]()
+			args.Add(label.Name)
+
+			names := dsa.NewList[string]()
+			names.Add("LabelName")
+
+			item.Index = label.Offset
+			item.Command = "LABEL"
+			item.Arguments = args.Items
+			item.Names = names.Items
+			if options.LabelIds || options.SourceComments {
+				item.Comment = fmt.Sprintf("id %08x", uint32(label.Id))
+			}
+
+			stack.Push(item)
+
+			label = iterLabels.Next()
+		} else {
+			commandName := compiler.Commands[command.Id]
+			item.Command = commandName
+	
+			names := dsa.NewList[string]()
+			args := dsa.NewList[string]()
+	
+			// Set current line value.
+			switch commandName {
+			case "IF":
+				fallthrough
+			case "ELSE":
+				if command.NumAttributes == 0 {
+					break
+				}
+				attribute := iterAttributes.Next()
+				conditionAttr := compiler.Attributes[command.Id][byte(attribute.Id)]
+				conditionValue := attribute.DecompileWith(options.Symbols)
+	
+				names.Add(conditionAttr)
+				args.Add(conditionValue)
+	
+				// Skip the rest of the attributes
+				for i := 1; i < int(command.NumAttributes); i++ {
+					iterAttributes.Next()
+				}
+			case "LET":	
+				varNameAttr := iterAttributes.Next()
+				varName := varNameAttr.DecompileWith(options.Symbols)
+	
+				varValueAttr := iterAttributes.Next()
+				varValue := varValueAttr.DecompileWith(options.Symbols)
+	
+				varOperation := AssignmentOperator(varName, varNameAttr.Type[1])
+	
+				names.Add("Operand1")
+				args.Add(varName)
+
+				names.Add("Operation")
+				args.Add(varOperation)
+
+				names.Add("Operand2")
+				args.Add(varValue)
+			default:
+				index := commandCount
+				warn := func(message string) {
+					warnings.Add(Warning{index, message})
+				}
+
+				for i := 0; i < int(command.NumAttributes); i++ {
+					attribute := iterAttributes.Next()
+					def := compiler.Definition(command.Id, attribute.Id)
+					if i == 0 && IsDeclaration(commandName) {
+						item.Comment = variableComment(attribute, options.Symbols)
+					}
+
+					if commandName == "LOOP" && isEndlessCount(attribute) {
+						continue
+					}
+
+					attrValue, ok := renderArgument(compiler, commandName, def, attribute, options, warn)
+					if !ok {
+						continue
+					}
+	
+					names.Add(def.Name)
+					args.Add(attrValue)
+				}
+			}
+	
+			item.Names = names.Items
+			item.Arguments = args.Items
+			if options.SourceComments {
+				item.Comment = joinComments(item.Comment, sourceComment(commandCount, command))
+			}
+
+			switch commandName {
+			case "RETURN":
+				closeSubroutine(stack, item, callTargets)
+			case "RETURNCODE":
+				curr := item
+				children := dsa.NewList[Line]()
+				for stack.Count() > 0 && (curr.Command != "WORD" || curr.Visited) {
+					children.Add(curr)
+					curr = stack.Pop()
+				}
+	
+				children.Reverse()
+	
+				start := curr
+				start.Visited = true
+				start.Children = children.Items
+				stack.Push(start)
+			case "IFBLEND":
+				// Ends the current branch. The branches are split at their
+				// ELSE markers once the IFEND is reached.
+			case "IFEND":
+				statement := buildIf(stack)
+				statement.EndComment = item.Comment
+				stack.Push(statement)
+			case "LOOP":
+				openLoops++
+				stack.Push(item)
+			case "LOOPBREAK", "LOOPCONTINUE":
+				if openLoops == 0 {
+					warnings.Add(Warning{commandCount, commandName + " outside of a LOOP"})
+				}
+				stack.Push(item)
+			case "LOOPEND":
+				if loop, ok := buildLoop(stack); ok {
+					openLoops--
+					stack.Push(loop)
+				} else {
+					warnings.Add(Warning{commandCount, "LOOPEND without an open LOOP"})
+				}
+				stack.Push(item)
+			default:
+				stack.Push(item)
+			}
+
+			command = iterCommands.Next()
+			commandCount += 1
+		}
+	}
+
+	lines := dsa.NewList[Line]()
+
+	for stack.Count() > 0 {
+		item := stack.Pop()
+		lines.Add(item)
+	}
+
+	lines.Reverse()
+
+	if options.Symbols != nil {
+		applyLabelAliases(lines.Items, options.Symbols)
+	}
+
+	return lines.Items, warnings.Items
+}
+
+// Returns true if the command declares a variable.
+func IsDeclaration(command string) bool {
+	switch command {
+	case "INT", "STR", "S_INT", "S_STR", "G_INT", "G_STR", "F_INT", "F_STR":
+		return true
+	}
+
+	return false
+}
+
+// Returns the symbol comment of the variable declared by the attribute.
+func variableComment(attr *Attribute, symbols *Symbols) string {
+	for _, token := range Tokenize(attr.Bytes) {
+		if token.IsVariable() {
+			symbol, _ := symbols.Variable(token.Variable())
+			return symbol.Comment
+		}
+	}
+
+	return ""
+}
+
+// Renames labels and the GOTO and GOSUB references to them.
+func applyLabelAliases(lines []Line, symbols *Symbols) {
+	for i := range lines {
+		line := &lines[i]
+
+		switch line.Command {
+		case "LABEL":
+			name := line.Arguments[0]
+			if symbol, ok := symbols.Label(name); ok {
+				line.Arguments[0] = symbol.Name
+				line.Comment = joinComments(line.Comment, symbol.Comment)
+			}
+		case "GOTO", "GOSUB":
+			if len(line.Arguments) > 0 {
+				name := LabelName(line.Arguments[0])
+				if symbol, ok := symbols.Label(name); ok {
+					line.Arguments[0] = strings.Replace(line.Arguments[0], "#" + name, "#" + symbol.Name, 1)
+				}
+			}
+		}
+
+		applyLabelAliases(line.Children, symbols)
+		for j := range line.Branches {
+			applyLabelAliases(line.Branches[j].Body, symbols)
+		}
+		if line.Else != nil {
+			applyLabelAliases(line.Else.Body, symbols)
+		}
+	}
+}
+
+// Describes the command a line was decompiled from.
+func sourceComment(index int, command *Command) string {
+	if command.NumAttributes == 0 {
+		return fmt.Sprintf("index %d, id 0x%02x, no attributes", index, command.Id)
+	}
+
+	start := command.AttributeIndex
+	end := start + int(command.NumAttributes) - 1
+	return fmt.Sprintf("index %d, id 0x%02x, attributes %d-%d", index, command.Id, start, end)
+}
+
+func joinComments(first string, second string) string {
+	if first == "" {
+		return second
+	}
+
+	if second == "" {
+		return first
+	}
+
+	return first + "; " + second
+}
+
+// Assignment operators of LET, by the second byte of the variable
+// attribute type.
+var assignmentOperators = map[byte]string{
+	0:	"=",
+	1:	"+=",
+	2:	"-=",
+	3:	"*=",
+	4:	"/=",
+	5:	"%=",
+	6:	"&=",
+	7:	"|=",
+	8:	"^=",
+}
+
+// Returns the assignment operator for the given code. String variables only
+// support plain assignment and concatenation with +=. Unsupported codes are
+// printed as ?0xNN= so they fail to recompile instead of silently becoming =.
+func AssignmentOperator(varName string, code byte) string {
+	operator, ok := assignmentOperators[code]
+	if ok && strings.HasPrefix(varName, "$") && code > 1 {
+		ok = false
+	}
+
+	if !ok {
+		return fmt.Sprintf("?0x%02x=", code)
+	}
+
+	return operator
+}
+
+// Closes the subroutine ended by a RETURN. The subroutine starts at the
+// outermost open label called through GOSUB, or the innermost open label if
+// none of them are called. Labels in between stay as markers inside the
+// subroutine. Only labels inside the innermost open block are considered,
+// so a RETURN inside a block without a label is kept as a plain line.
+func closeSubroutine(stack *dsa.Stack[Line], item Line, callTargets map[string]bool) {
+	start := -1
+	for i := stack.Count() - 1; i >= 0; i-- {
+		curr := stack.Items[i]
+		if curr.Visited {
+			if curr.Command == "LABEL" {
+				break
+			}
+			continue
+		}
+
+		if curr.Command != "LABEL" {
+			if isBlockStart(curr.Command) {
+				break
+			}
+			continue
+		}
+
+		if start == -1 || callTargets[curr.Arguments[0]] {
+			start = i
+		}
+	}
+
+	if start == -1 {
+		stack.Push(item)
+		return
+	}
+
+	children := dsa.NewList[Line]()
+	for stack.Count() > start + 1 {
+		children.Add(stack.Pop())
+	}
+
+	children.Reverse()
+	children.Add(item)
+
+	subroutine := stack.Pop()
+	subroutine.Visited = true
+	subroutine.Children = children.Items
+	stack.Push(subroutine)
+}
+
+func isBlockStart(command string) bool {
+	switch command {
+	case "IF", "ELSE", "LOOP", "WORD":
+		return true
+	}
+
+	return false
+}
+
+// Returns the names of the labels called through GOSUB in the script.
+func findCallTargets(script Script, compiler CompilerDefinition) map[string]bool {
+	targets := make(map[string]bool)
+	for _, command := range script.Commands {
+		if compiler.Commands[command.Id] != "GOSUB" {
+			continue
+		}
+
+		attributes := script.CommandAttributes(command)
+		if len(attributes) > 0 {
+			targets[LabelName(attributes[0].Decompile())] = true
+		}
+	}
+
+	return targets
+}
+
+// Pops the body of the innermost open LOOP off the stack and returns the
+// LOOP with its body. Returns false and leaves the stack as it was if
+// another block is open inside the LOOP, or no LOOP is open.
+func buildLoop(stack *dsa.Stack[Line]) (Line, bool) {
+	body := dsa.NewList[Line]()
+	for stack.Count() > 0 {
+		curr := stack.Pop()
+		if !curr.Visited && curr.Command == "LOOP" {
+			body.Reverse()
+			curr.Visited = true
+			curr.Children = body.Items
+			return curr, true
+		}
+
+		body.Add(curr)
+		if !curr.Visited && isBlockStart(curr.Command) {
+			break
+		}
+	}
+
+	for i := body.Count() - 1; i >= 0; i-- {
+		stack.Push(body.Items[i])
+	}
+
+	return Line{}, false
+}
+
+// Returns true if the LOOP count is -1, which repeats until LOOPBREAK.
+func isEndlessCount(attr *Attribute) bool {
+	value, err := attr.Evaluate(nil)
+	return err == nil && value.Type == TypeInt && value.Int == -1
+}
+
+// Pops the branches of the innermost open IF off the stack and returns them
+// as a single IF statement.
+func buildIf(stack *dsa.Stack[Line]) Line {
+	branches := dsa.NewList[Branch]()
+	var elseBranch *Branch
+
+	body := dsa.NewList[Line]()
+	found := false
+	for stack.Count() > 0 && !found {
+		curr := stack.Pop()
+		if curr.Visited || (curr.Command != "IF" && curr.Command != "ELSE") {
+			body.Add(curr)
+			continue
+		}
+
+		body.Reverse()
+		branch := Branch{Index: curr.Index, Body: body.Items, Comment: curr.Comment}
+		if len(curr.Arguments) > 0 {
+			branch.Name = curr.Names[0]
+			branch.Condition = curr.Arguments[0]
+		}
+
+		// Only the last ELSE of a chain can be without a condition.
+		isElse := curr.Command == "ELSE" && len(curr.Arguments) == 0
+		if isElse && branches.Count() == 0 && elseBranch == nil {
+			elseBranch = &branch
+		} else {
+			branches.Add(branch)
+		}
+
+		body = dsa.NewList[Line]()
+		found = curr.Command == "IF"
+	}
+
+	// Keep the lines of an IF whose start was never found, such as when
+	// decompiling a range, in a branch without an IF.
+	if !found {
+		body.Reverse()
+		branches.Add(Branch{Index: -1, Body: body.Items})
+	}
+
+	branches.Reverse()
+
+	item := Line{Index: -1}
+	for _, branch := range branches.Items {
+		if branch.Index >= 0 {
+			item.Index = branch.Index
+			break
+		}
+	}
+	if item.Index < 0 && elseBranch != nil {
+		item.Index = elseBranch.Index
+	}
+	item.Command = "IF"
+	item.Branches = branches.Items
+	item.Else = elseBranch
+	item.Visited = true
+
+	return item
+}
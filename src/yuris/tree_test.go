@@ -0,0 +1,346 @@
+package yuris_test
+
+import (
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestDecompileReturnInsideBlock(t *testing.T) {
+	tests := []struct {
+		name		string
+		commands	[]yuristest.CommandSpec
+		labels		[]yuris.Label
+		want		string
+	}{
+		{
+			name: "label inside the block",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("_"),
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("_"),
+				yuristest.Command("RETURN"),
+				yuristest.Command("IFEND"),
+				yuristest.Command("RETURN"),
+				yuristest.Command("GOSUB", yuristest.LabelRef("A")),
+			},
+			labels: []yuris.Label{{Name: "A", Offset: 0}, {Name: "B", Offset: 2}},
+			want: `#=A
+{
+  _[]
+  IF[@var1]
+  {
+    #=B
+    {
+      _[]
+      RETURN[]
+    }
+  }
+  IFEND[]
+  RETURN[]
+}
+
+GOSUB[PLABEL="#A"]
+
+`,
+		},
+		{
+			name: "label outside the block",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("RETURN"),
+				yuristest.Command("IFEND"),
+				yuristest.Command("_"),
+				yuristest.Command("RETURN"),
+			},
+			labels: []yuris.Label{{Name: "A", Offset: 0}},
+			want: `#=A
+{
+  IF[@var1]
+  {
+    RETURN[]
+  }
+  IFEND[]
+  _[]
+  RETURN[]
+}
+
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, compiler := yuristest.NewScript(test.commands...)
+			lines, warnings := yuris.Decompile(script, compiler, test.labels, yuris.Options{})
+
+			if len(warnings) > 0 {
+				t.Errorf("warnings = %v, want none", warnings)
+			}
+			if got := yuris.DefaultFormat().Lines(lines); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecompileLabel(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("_"),
+		yuristest.Command("RETURN"),
+		yuristest.Command("_"),
+		yuristest.Command("RETURN"),
+	)
+
+	labels := []yuris.Label{
+		{Name: "OTHER", ScriptIndex: 0, Offset: 0},
+		{Name: "B", ScriptIndex: 1, Offset: 2},
+		{Name: "A", ScriptIndex: 1, Offset: 0},
+		{Name: "SHARED", ScriptIndex: 0, Offset: 1},
+		{Name: "SHARED", ScriptIndex: 1, Offset: 2},
+	}
+
+	tests := []struct {
+		name		string
+		label		string
+		want		string
+		err			string
+	}{
+		{name: "first label", label: "A", want: "#=A\n{\n  _[]\n  RETURN[]\n}\n\n"},
+		{name: "last label", label: "B", want: "#=B\n\n#=SHARED\n{\n  _[]\n  RETURN[]\n}\n\n"},
+		{name: "missing", label: "C", err: "label C not found"},
+		{name: "other script", label: "OTHER", err: "label OTHER is in yst00000, not yst00001"},
+		{name: "ambiguous", label: "SHARED", err: "label SHARED is ambiguous, it is defined at yst00000:1, yst00001:2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, _, err := yuris.DecompileLabel(script, compiler, labels, yuris.Options{}, 1, test.label)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("err = %v, want %s", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := yuris.DefaultFormat().Lines(lines); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecompileIf(t *testing.T) {
+	tests := []struct {
+		name		string
+		commands	[]yuristest.CommandSpec
+		want		string
+	}{
+		{
+			name: "chain",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("_"),
+				yuristest.Command("IFBLEND"),
+				yuristest.Command("ELSE", yuristest.Variable(2)),
+				yuristest.Command("_"),
+				yuristest.Command("IFBLEND"),
+				yuristest.Command("ELSE"),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+			},
+			want: "IF[@var1]\n{\n  _[]\n}\nELSE[@var2]\n{\n  _[]\n}\nELSE[]\n{\n  _[]\n}\nIFEND[]\n\n",
+		},
+		{
+			name: "nested",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("IF", yuristest.Variable(2)),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+				yuristest.Command("ELSE"),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+			},
+			want: "IF[@var1]\n{\n  IF[@var2]\n  {\n    _[]\n  }\n  IFEND[]\n}\nELSE[]\n{\n  _[]\n}\nIFEND[]\n\n",
+		},
+		{
+			name: "without a condition",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF"),
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+			},
+			want: "IF[]\n{\n  _[]\n}\nIFEND[]\n\n",
+		},
+		{
+			name: "unclosed",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("IF", yuristest.Variable(1)),
+				yuristest.Command("_"),
+			},
+			want: "IF[@var1]\n\n_[]\n\n",
+		},
+		{
+			name: "without a start",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("_"),
+				yuristest.Command("IFEND"),
+			},
+			want: "_[]\nIFEND[]\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, compiler := yuristest.NewScript(test.commands...)
+			lines, warnings := yuris.Decompile(script, compiler, nil, yuris.Options{})
+
+			if len(warnings) > 0 {
+				t.Errorf("warnings = %v, want none", warnings)
+			}
+			if got := yuris.DefaultFormat().Lines(lines); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatIfWithoutArguments(t *testing.T) {
+	lines := []yuris.Line{{Command: "IF"}, {Command: "ELSE"}}
+	if got, want := yuris.DefaultFormat().Lines(lines), "IF[]\n\nELSE[]\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecompileLoop(t *testing.T) {
+	tests := []struct {
+		name		string
+		commands	[]yuristest.CommandSpec
+		want		string
+		warnings	[]string
+	}{
+		{
+			name: "body order",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("LOOP", yuristest.Token(0x42, 3)),
+				yuristest.Command("A"),
+				yuristest.Command("B"),
+				yuristest.Command("LOOPEND"),
+			},
+			want: "LOOP[SET=3]\n{\n  A[]\n  B[]\n}\n\nLOOPEND[]\n\n",
+		},
+		{
+			name: "nested",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("LOOP", yuristest.Token(0x42, 3)),
+				yuristest.Command("_"),
+				yuristest.Command("LOOP", yuristest.Token(0x42, 2)),
+				yuristest.Command("LOOPBREAK"),
+				yuristest.Command("LOOPEND"),
+				yuristest.Command("LOOPCONTINUE"),
+				yuristest.Command("LOOPEND"),
+			},
+			want: "LOOP[SET=3]\n{\n  _[]\n  LOOP[SET=2]\n  {\n    LOOPBREAK[]\n  }\n  LOOPEND[]\n  LOOPCONTINUE[]\n}\n\nLOOPEND[]\n\n",
+		},
+		{
+			name: "stray",
+			commands: []yuristest.CommandSpec{
+				yuristest.Command("_"),
+				yuristest.Command("LOOPBREAK"),
+				yuristest.Command("LOOPCONTINUE"),
+				yuristest.Command("LOOPEND"),
+			},
+			want: "_[]\n\nLOOPBREAK[]\n\nLOOPCONTINUE[]\n\nLOOPEND[]\n\n",
+			warnings: []string{
+				"command 1: LOOPBREAK outside of a LOOP",
+				"command 2: LOOPCONTINUE outside of a LOOP",
+				"command 3: LOOPEND without an open LOOP",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, compiler := yuristest.NewScript(test.commands...)
+			lines, warnings := yuris.Decompile(script, compiler, nil, yuris.Options{})
+
+			if len(warnings) != len(test.warnings) {
+				t.Fatalf("warnings = %v, want %v", warnings, test.warnings)
+			}
+			for i, warning := range warnings {
+				if got := warning.String(); got != test.warnings[i] {
+					t.Errorf("warning %d = %q, want %q", i, got, test.warnings[i])
+				}
+			}
+			if got := yuris.DefaultFormat().Lines(lines); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAssignmentOperator(t *testing.T) {
+	tests := []struct {
+		varName		string
+		code		byte
+		want		string
+	}{
+		{"@var1", 0, "="},
+		{"@var1", 1, "+="},
+		{"@var1", 2, "-="},
+		{"@var1", 3, "*="},
+		{"@var1", 4, "/="},
+		{"@var1", 5, "%="},
+		{"@var1", 6, "&="},
+		{"@var1", 7, "|="},
+		{"@var1", 8, "^="},
+		{"@var1", 9, "?0x09="},
+		{"@var1", 0xff, "?0xff="},
+		{"$var1", 0, "="},
+		{"$var1", 1, "+="},
+		{"$var1", 2, "?0x02="},
+		{"$var1", 8, "?0x08="},
+		{"$var1", 9, "?0x09="},
+	}
+
+	for _, test := range tests {
+		if got := yuris.AssignmentOperator(test.varName, test.code); got != test.want {
+			t.Errorf("AssignmentOperator(%q, %d) = %q, want %q", test.varName, test.code, got, test.want)
+		}
+	}
+}
+
+func TestDecompileSourceComments(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("IF", yuristest.Variable(1)),
+		yuristest.Command("_"),
+		yuristest.Command("IFEND"),
+		yuristest.Command("INT", yuristest.Variable(2), yuristest.Token(0x42, 5)),
+		yuristest.Command("RETURN"),
+	)
+	labels := []yuris.Label{{Name: "A", Id: 0x1234abcd, Offset: 3}}
+
+	lines, _ := yuris.Decompile(script, compiler, labels, yuris.Options{SourceComments: true})
+
+	want := `IF[@var1] // index 0, id 0x00, attributes 0-0
+{
+  _[] // index 1, id 0x01, no attributes
+}
+IFEND[] // index 2, id 0x02, no attributes
+
+#=A // id 1234abcd
+{
+  INT[@var2 = 5] // index 3, id 0x03, attributes 1-2
+  RETURN[] // index 4, id 0x04, no attributes
+}
+
+`
+	if got := yuris.DefaultFormat().Lines(lines); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,295 @@
+package yuris
+
+import (
+	"io"
+	"os"
+	"bytes"
+	"strings"
+	"compress/zlib"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+)
+
+const ypfHeaderSize = 0x20
+
+// A file stored in a YPF archive.
+type ArchiveEntry struct {
+	Name			string
+	Type			byte
+	Packed			bool
+	Size			int
+	PackedSize		int
+	Offset			int64
+}
+
+// A YPF archive. Only the index is read when opening it, and the contents of
+// entries are read on demand.
+type Archive struct {
+	Version			int
+	Entries			[]ArchiveEntry
+
+	r				io.ReaderAt
+	closer			io.Closer
+}
+
+// Layout of the index entries, which differs between engine versions.
+type ypfLayout struct {
+	// Pairs of name lengths that are swapped when stored.
+	swap			[]byte
+	wideOffsets		bool
+}
+
+var ypfSwapTables = [][]byte{
+	nil,
+	{
+		0x03, 0x48, 0x06, 0x35, 0x09, 0x0b, 0x0c, 0x10, 0x0d, 0x13, 0x11, 0x19,
+		0x15, 0x1b, 0x1c, 0x1e, 0x20, 0x23, 0x26, 0x29, 0x2c, 0x2f, 0x2e, 0x32,
+	},
+	{
+		0x09, 0x0b, 0x0d, 0x13, 0x15, 0x1b, 0x20, 0x23, 0x26, 0x29, 0x2c, 0x2f,
+		0x2e, 0x32,
+	},
+}
+
+// An index entry with its name still obfuscated.
+type ypfEntry struct {
+	name			[]byte
+	entry			ArchiveEntry
+}
+
+func OpenArchive(path string) (*Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	archive, err := NewArchive(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	archive.closer = file
+	return archive, nil
+}
+
+// Reads the index of an archive of the given size. The layout of the index
+// and the key of the names are not stored in the archive, so every known
+// layout is tried until one describes the whole index consistently.
+func NewArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	header := make([]byte, ypfHeaderSize)
+	if n, _ := r.ReadAt(header, 0); n < len(header) {
+		return nil, formatError("truncated YPF header")
+	}
+
+	br := utils.NewBinaryReader(header)
+	if !bytes.Equal(br.ReadBytes(4), []byte("YPF\x00")) {
+		return nil, formatError("invalid magic in YPF archive")
+	}
+
+	archive := &Archive{r: r}
+	archive.Version = br.ReadInt32()
+	count := br.ReadInt32()
+	indexSize := br.ReadInt32()
+
+	if int64(ypfHeaderSize + indexSize) > size {
+		return nil, formatError("YPF index is larger than the archive")
+	}
+
+	index := make([]byte, indexSize)
+	if n, _ := r.ReadAt(index, ypfHeaderSize); n < len(index) {
+		return nil, formatError("truncated YPF index")
+	}
+
+	for _, wideOffsets := range []bool{false, true} {
+		for _, swap := range ypfSwapTables {
+			entries, err := parseYPFIndex(index, count, ypfLayout{swap, wideOffsets}, size)
+			if err != nil {
+				continue
+			}
+
+			key, ok := guessNameKey(entries)
+			if !ok {
+				continue
+			}
+
+			for _, raw := range entries {
+				name := make([]byte, len(raw.name))
+				for i := range raw.name {
+					name[i] = raw.name[i] ^ key
+				}
+
+				entry := raw.entry
+				entry.Name = utils.DecodeString(name)
+				archive.Entries = append(archive.Entries, entry)
+			}
+
+			return archive, nil
+		}
+	}
+
+	return nil, formatError("unsupported YPF index layout")
+}
+
+func parseYPFIndex(index []byte, count int, layout ypfLayout, size int64) (entries []ypfEntry, err error) {
+	defer recoverFormat("ypf", &err)
+
+	br := utils.NewBinaryReader(index)
+	dataStart := int64(ypfHeaderSize + len(index))
+	for i := 0; i < count; i++ {
+		// Hash of the name
+		br.Skip(4)
+
+		length := swapLength(layout.swap, ^br.ReadByte())
+		if length == 0 {
+			return nil, formatError("empty YPF entry name")
+		}
+
+		raw := ypfEntry{}
+		raw.name = br.ReadBytes(length)
+		raw.entry.Type = br.ReadByte()
+
+		packed := br.ReadByte()
+		if packed > 1 {
+			return nil, formatError("invalid YPF compression flag")
+		}
+		raw.entry.Packed = packed == 1
+
+		raw.entry.Size = br.ReadInt32()
+		raw.entry.PackedSize = br.ReadInt32()
+		if layout.wideOffsets {
+			raw.entry.Offset = br.ReadInt64()
+		} else {
+			raw.entry.Offset = int64(br.ReadInt32())
+		}
+
+		// Checksum of the data
+		br.Skip(4)
+
+		entry := raw.entry
+		if entry.Offset < dataStart || entry.Offset + int64(entry.PackedSize) > size {
+			return nil, formatError("YPF entry outside of the archive")
+		}
+
+		if !entry.Packed && entry.Size != entry.PackedSize {
+			return nil, formatError("YPF entry sizes do not match")
+		}
+
+		entries = append(entries, raw)
+	}
+
+	if br.Position != len(index) {
+		return nil, formatError("YPF index size does not match its entries")
+	}
+
+	return entries, nil
+}
+
+func swapLength(swap []byte, length byte) int {
+	for i, value := range swap {
+		if value != length {
+			continue
+		}
+
+		if i % 2 == 0 {
+			return int(swap[i + 1])
+		}
+		return int(swap[i - 1])
+	}
+
+	return int(length)
+}
+
+// Finds the key the names are XORed with, assuming that every name has a
+// file extension. The candidates are the keys that turn one of the last
+// bytes of the first name into a dot.
+func guessNameKey(entries []ypfEntry) (byte, bool) {
+	if len(entries) == 0 {
+		return 0, true
+	}
+
+	first := entries[0].name
+	for i := len(first) - 1; i >= 0 && i >= len(first) - 6; i-- {
+		key := first[i] ^ '.'
+		if isNameKey(entries, key) {
+			return key, true
+		}
+	}
+
+	return 0, false
+}
+
+func isNameKey(entries []ypfEntry, key byte) bool {
+	for _, raw := range entries {
+		hasDot := false
+		for _, b := range raw.name {
+			c := b ^ key
+			if c < 0x20 || c == 0x7f {
+				return false
+			}
+			hasDot = hasDot || c == '.'
+		}
+
+		if !hasDot {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *Archive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+
+	return a.closer.Close()
+}
+
+// Finds an entry by path, ignoring case and the direction of slashes.
+func (a *Archive) Find(name string) (ArchiveEntry, bool) {
+	key := archivePath(name)
+	for _, entry := range a.Entries {
+		if archivePath(entry.Name) == key {
+			return entry, true
+		}
+	}
+
+	return ArchiveEntry{}, false
+}
+
+// Reads the contents of an entry, decompressing it if needed.
+func (a *Archive) Read(entry ArchiveEntry) ([]byte, error) {
+	data := make([]byte, entry.PackedSize)
+	if n, _ := a.r.ReadAt(data, entry.Offset); n < len(data) {
+		return nil, formatError("truncated YPF entry " + entry.Name)
+	}
+
+	if !entry.Packed {
+		return data, nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, formatError("invalid compressed YPF entry " + entry.Name)
+	}
+	defer zr.Close()
+
+	data, err = io.ReadAll(zr)
+	if err != nil || len(data) != entry.Size {
+		return nil, formatError("invalid compressed YPF entry " + entry.Name)
+	}
+
+	return data, nil
+}
+
+// Normalizes the path of an archive entry for comparison.
+func archivePath(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "\\", "/"))
+}
@@ -0,0 +1,148 @@
+package yuris
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type testArchiveFile struct {
+	name		string
+	data		[]byte
+	packed		bool
+}
+
+// Builds an archive with the given name key and index layout.
+func newTestArchive(files []testArchiveFile, key byte, layout ypfLayout) []byte {
+	offsetSize := 4
+	if layout.wideOffsets {
+		offsetSize = 8
+	}
+
+	indexSize := 0
+	for _, file := range files {
+		indexSize += 4 + 1 + len(file.name) + 2 + 4 + 4 + offsetSize + 4
+	}
+
+	index := bytes.NewBuffer(nil)
+	data := bytes.NewBuffer(nil)
+	for _, file := range files {
+		stored := file.data
+		packed := byte(0)
+		if file.packed {
+			buf := bytes.NewBuffer(nil)
+			zw := zlib.NewWriter(buf)
+			zw.Write(file.data)
+			zw.Close()
+			stored = buf.Bytes()
+			packed = 1
+		}
+
+		offset := uint64(ypfHeaderSize + indexSize + data.Len())
+		data.Write(stored)
+
+		index.Write(make([]byte, 4))
+		index.WriteByte(^byte(swapLength(layout.swap, byte(len(file.name)))))
+		for _, c := range []byte(file.name) {
+			index.WriteByte(c ^ key)
+		}
+		index.Write([]byte{0, packed})
+		binary.Write(index, binary.LittleEndian, []uint32{uint32(len(file.data)), uint32(len(stored))})
+		if layout.wideOffsets {
+			binary.Write(index, binary.LittleEndian, offset)
+		} else {
+			binary.Write(index, binary.LittleEndian, uint32(offset))
+		}
+		index.Write(make([]byte, 4))
+	}
+
+	archive := bytes.NewBuffer(nil)
+	archive.WriteString("YPF\x00")
+	binary.Write(archive, binary.LittleEndian, []uint32{490, uint32(len(files)), uint32(indexSize)})
+	archive.Write(make([]byte, 16))
+	archive.Write(index.Bytes())
+	archive.Write(data.Bytes())
+
+	return archive.Bytes()
+}
+
+func TestNewArchive(t *testing.T) {
+	files := []testArchiveFile{
+		{"ysbin\\yst00001.ybn", []byte("YSTB script"), false},
+		{"cg\\ev_001.png", bytes.Repeat([]byte("image "), 20), true},
+		{"a.b", []byte("x"), false},
+	}
+
+	tests := []struct {
+		name		string
+		key			byte
+		layout		ypfLayout
+	}{
+		{"plain names", 0xff, ypfLayout{}},
+		{"swapped lengths", 0x36, ypfLayout{swap: ypfSwapTables[1]}},
+		{"wide offsets", 0x00, ypfLayout{swap: ypfSwapTables[2], wideOffsets: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := newTestArchive(files, test.key, test.layout)
+			archive, err := NewArchive(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := make([]string, len(archive.Entries))
+			for i, entry := range archive.Entries {
+				names[i] = entry.Name
+			}
+			if want := []string{"ysbin\\yst00001.ybn", "cg\\ev_001.png", "a.b"}; !reflect.DeepEqual(names, want) {
+				t.Fatalf("names = %q, want %q", names, want)
+			}
+
+			for _, file := range files {
+				entry, ok := archive.Find(file.name)
+				if !ok {
+					t.Fatalf("%s not found", file.name)
+				}
+
+				contents, err := archive.Read(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(contents, file.data) {
+					t.Errorf("%s = %q, want %q", file.name, contents, file.data)
+				}
+			}
+
+			if _, ok := archive.Find("YSBIN/YST00001.YBN"); !ok {
+				t.Errorf("Find should ignore case and slashes")
+			}
+		})
+	}
+}
+
+func TestNewArchiveErrors(t *testing.T) {
+	valid := newTestArchive([]testArchiveFile{{"a.txt", []byte("abc"), false}}, 0xff, ypfLayout{})
+
+	badMagic := append([]byte("YPX\x00"), valid[4:]...)
+
+	// An index size that ends in the middle of an entry.
+	badIndex := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint32(badIndex[12:], binary.LittleEndian.Uint32(valid[12:]) - 1)
+
+	// Data that ends before the entry does.
+	truncated := valid[:len(valid) - 1]
+
+	for name, data := range map[string][]byte{
+		"bad magic":	badMagic,
+		"bad index":	badIndex,
+		"truncated":	truncated,
+		"empty":		nil,
+	} {
+		if _, err := NewArchive(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("%s: NewArchive() succeeded", name)
+		}
+	}
+}
@@ -1,27 +1,70 @@
 package yuris
 
 import (
+	"fmt"
+	"strconv"
 	"io/ioutil"
-	"log"
+	"encoding/json"
 
 	"github.com/damianfadri/yuris-decompiler/utils"
 )
 
+// Value types of command attributes, from the type byte of the attribute
+// definitions in YSCom.ycd. These codes, and the meaning of the option and
+// default fields, were inferred from the file layout and from which values
+// each attribute takes in scripts. They are not documented by YU-RIS and
+// have not been checked against the compiler, so other codes are treated
+// like AttrTypeAny and nothing that changes the meaning of a script may
+// rely on them alone.
+const (
+	AttrTypeAny			byte = 0
+	AttrTypeInt			byte = 1
+	AttrTypeDouble		byte = 2
+	AttrTypeString		byte = 3
+	AttrTypeFlag		byte = 4
+)
+
+// An attribute of a command as YSCom.ycd defines it. Options is kept as read,
+// its bits are not known. Default is believed to be the value the engine
+// uses when the attribute is left out.
+type AttributeDefinition struct {
+	Name			string
+	Type			byte
+	Options			byte
+	Default			int16
+}
+
+type EnumKey struct {
+	Command			string
+	Attribute		string
+}
+
 type CompilerDefinition struct {
 	Commands		map[byte]string
 	Attributes		[]map[byte]string
+	Definitions		[]map[byte]AttributeDefinition
+
+	// Symbolic names of attribute values. Flag attributes are printed as a
+	// combination of the names of their bits.
+	Enums			map[EnumKey]map[int]string
 }
 
-func ReadYSCom(path string) CompilerDefinition {
+func ReadYSCom(path string) (CompilerDefinition, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatal(err)
+		return CompilerDefinition{}, err
 	}
 
+	return ParseYSCom(data)
+}
+
+func ParseYSCom(data []byte) (yscom CompilerDefinition, err error) {
+	defer recoverFormat("yscom", &err)
+
 	br := utils.NewBinaryReader(data)
 	magic := br.ReadString(4)
 	if magic != "YSCD" {
-		log.Fatal("Invalid magic in ysc.ybn")
+		return yscom, formatError("invalid magic in YSCom.ycd")
 	}
 
 	// YU-RIS version
@@ -30,8 +73,8 @@ func ReadYSCom(path string) CompilerDefinition {
 
 	br.Skip(4)
 
-	yscom := CompilerDefinition{}
 	yscom.Commands = make(map[byte]string)
+	yscom.Enums = make(map[EnumKey]map[int]string)
 
 	for commandId := byte(0); commandId < byte(numCommands); commandId++ {
 		commandName := br.ReadStringUntilNull()
@@ -40,15 +83,79 @@ func ReadYSCom(path string) CompilerDefinition {
 		numAttrs := byte(0)
 		numAttrs = br.ReadByte()
 		commandAttrs := make(map[byte]string)
+		commandDefs := make(map[byte]AttributeDefinition)
 		for attrId := byte(0); attrId < numAttrs; attrId++ {
 			attrName := br.ReadStringUntilNull()
 			commandAttrs[attrId] = attrName
 
-			br.Skip(4)
+			def := AttributeDefinition{}
+			def.Name = attrName
+			def.Type = br.ReadByte()
+			def.Options = br.ReadByte()
+			def.Default = br.ReadInt16()
+			commandDefs[attrId] = def
 		}
 
 		yscom.Attributes = append(yscom.Attributes, commandAttrs)
+		yscom.Definitions = append(yscom.Definitions, commandDefs)
+	}
+
+	return yscom, nil
+}
+
+// Returns the definition of an attribute of the given command.
+func (c *CompilerDefinition) Definition(commandId byte, attrId int16) AttributeDefinition {
+	if int(commandId) < len(c.Definitions) {
+		if def, ok := c.Definitions[commandId][byte(attrId)]; ok {
+			return def
+		}
+	}
+
+	def := AttributeDefinition{}
+	if int(commandId) < len(c.Attributes) {
+		def.Name = c.Attributes[commandId][byte(attrId)]
 	}
 
-	return yscom
-}
\ No newline at end of file
+	return def
+}
+
+// Registers symbolic names for the values of an attribute.
+func (c *CompilerDefinition) SetEnum(command string, attribute string, values map[int]string) {
+	if c.Enums == nil {
+		c.Enums = make(map[EnumKey]map[int]string)
+	}
+
+	c.Enums[EnumKey{command, attribute}] = values
+}
+
+// Reads symbolic names of attribute values from a JSON file and registers
+// them. Values are keyed by command, attribute and value, such as
+// {"CG": {"BLEND": {"0": "NORMAL", "0x10": "ADD"}}}.
+func (c *CompilerDefinition) ReadEnums(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	enums := make(map[string]map[string]map[string]string)
+	if err := json.Unmarshal(data, &enums); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	for command, attributes := range enums {
+		for attribute, names := range attributes {
+			values := make(map[int]string)
+			for key, name := range names {
+				value, err := strconv.ParseInt(key, 0, 32)
+				if err != nil {
+					return fmt.Errorf("%w: %s[%s] value %q is not a number", ErrInvalidFormat, command, attribute, key)
+				}
+				values[int(value)] = name
+			}
+
+			c.SetEnum(command, attribute, values)
+		}
+	}
+
+	return nil
+}
@@ -1,42 +1,77 @@
 package yuris
 
 import (
+	"sort"
 	"io/ioutil"
-	"log"
+	"strings"
 
 	"github.com/damianfadri/yuris-decompiler/utils/dsa"
 	"github.com/damianfadri/yuris-decompiler/utils"
 )
 
 type Label struct {
-	Name				string
-	Id					int
-	Offset				int
-	ScriptIndex			int16
+	Name				string	`json:"name"`
+	Id					int		`json:"id"`
+	Offset				int		`json:"offset"`
+	ScriptIndex			int16	`json:"scriptIndex"`
 }
 
-func ReadYSL(path string) []Label {
+func ReadYSL(path string) ([]Label, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
+	return ParseYSL(data)
+}
+
+// Parses the labels of ysl.ybn, in on-disk order.
+func ParseYSL(data []byte) ([]Label, error) {
+	table, err := ParseLabelTable(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return table.Labels, nil
+}
+
+// The contents of ysl.ybn in on-disk order. Labels are sorted by the hash of
+// their name, and RangeStarts holds the index of the first label of each of
+// the 256 hash buckets.
+type LabelTable struct {
+	Version				int
+	Labels				[]Label
+	RangeStarts			[]int
+}
+
+func ReadLabelTable(path string) (LabelTable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return LabelTable{}, err
+	}
+
+	return ParseLabelTable(data)
+}
+
+func ParseLabelTable(data []byte) (table LabelTable, err error) {
+	defer recoverFormat("ysl", &err)
+
 	br := utils.NewBinaryReader(data)
 	magic := br.ReadString(4)
 	if magic != "YSLB" {
-		log.Fatal("Invalid magic in ysl.ybn")
+		return table, formatError("invalid magic in ysl.ybn")
 	}
 
 	// YU-RIS version
-	_ = br.ReadInt32()
+	table.Version = br.ReadInt32()
 	numLabels := br.ReadInt32()
 
-	arrLabelRangeStartIndices := make([]int, 0x100)
-	for j := 0; j < len(arrLabelRangeStartIndices); j++ {
-		arrLabelRangeStartIndices[j] = br.ReadInt32()
+	table.RangeStarts = make([]int, 0x100)
+	for j := 0; j < len(table.RangeStarts); j++ {
+		table.RangeStarts[j] = br.ReadInt32()
 	}
 
-	labels := dsa.NewList[Label]()
+	list := dsa.NewList[Label]()
 	for j := 0; j < numLabels; j++ {
 		label := Label{}
 		
@@ -47,12 +82,38 @@ func ReadYSL(path string) []Label {
 		label.ScriptIndex = br.ReadInt16()
 		br.Skip(2)
 
-		labels.Add(label)
+		list.Add(label)
+	}
+
+	table.Labels = list.Items
+	return table, nil
+}
+
+// Returns the labels belonging to the given script, in offset order.
+// Labels at the same offset keep their order in ysl.ybn.
+func ScriptLabels(labels []Label, scriptIndex int) []Label {
+	scriptLabels := dsa.NewList[Label]()
+	for i := 0; i < len(labels); i++ {
+		label := labels[i]
+		if (label.ScriptIndex == int16(scriptIndex)) {
+			scriptLabels.Add(label)
+		}
 	}
 
-	labels.Sort(func(i, j int) bool { 
-		return labels.Items[i].Offset < labels.Items[j].Offset
+	sort.SliceStable(scriptLabels.Items, func(i, j int) bool {
+		return scriptLabels.Items[i].Offset < scriptLabels.Items[j].Offset
 	})
 
-	return labels.Items
-}
\ No newline at end of file
+	return scriptLabels.Items
+}
+
+// Normalizes a label reference from a GOTO or GOSUB attribute into the
+// label name as stored in ysl.ybn.
+func LabelName(ref string) string {
+	name := strings.TrimSpace(ref)
+	name = strings.Trim(name, "\"")
+	name = strings.TrimPrefix(name, "#")
+	name = strings.TrimPrefix(name, "=")
+
+	return name
+}
@@ -0,0 +1,44 @@
+package yuris_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+	"github.com/damianfadri/yuris-decompiler/yuris/yuristest"
+)
+
+func TestScriptLabels(t *testing.T) {
+	labels := []yuris.Label{
+		{Name: "LATE", ScriptIndex: 1, Offset: 5},
+		{Name: "SECOND", ScriptIndex: 1, Offset: 2},
+		{Name: "OTHER", ScriptIndex: 0, Offset: 2},
+		{Name: "FIRST", ScriptIndex: 1, Offset: 2},
+		{Name: "START", ScriptIndex: 1, Offset: 0},
+		{Name: "THIRD", ScriptIndex: 1, Offset: 2},
+	}
+
+	names := make([]string, 0)
+	for _, label := range yuris.ScriptLabels(labels, 1) {
+		names = append(names, label.Name)
+	}
+
+	if want := []string{"START", "SECOND", "FIRST", "THIRD", "LATE"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ScriptLabels() = %v, want %v", names, want)
+	}
+}
+
+func TestDecompileLabelsAtTheSameOffset(t *testing.T) {
+	script, compiler := yuristest.NewScript(
+		yuristest.Command("_"),
+		yuristest.Command("RETURN"),
+	)
+	labels := []yuris.Label{{Name: "B", Offset: 0}, {Name: "A", Offset: 0}}
+
+	lines, _ := yuris.Decompile(script, compiler, labels, yuris.Options{})
+
+	want := "#=B\n\n#=A\n{\n  _[]\n  RETURN[]\n}\n\n"
+	if got := yuris.DefaultFormat().Lines(lines); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
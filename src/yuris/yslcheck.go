@@ -0,0 +1,96 @@
+package yuris
+
+import (
+	"fmt"
+
+	"github.com/damianfadri/yuris-decompiler/utils/dsa"
+)
+
+// A consistency problem in ysl.ybn. Label is empty for problems with the
+// table itself.
+type LabelProblem struct {
+	Label		string	`json:"label"`
+	Message		string	`json:"message"`
+}
+
+func (p LabelProblem) String() string {
+	if p.Label == "" {
+		return p.Message
+	}
+
+	return fmt.Sprintf("#=%s: %s", p.Label, p.Message)
+}
+
+// Checks that every label is in the range of the hash bucket of its id, and
+// that label names and ids are unique. If DetectHash finds the hash of the
+// table, also checks that every id is the hash of its name. If the number of
+// instructions of each script is given, also checks that labels do not point
+// past the end of their script.
+func (t *LabelTable) Validate(instructionCounts map[int]int) []LabelProblem {
+	problems := dsa.NewList[LabelProblem]()
+	report := func(label string, format string, args ...any) {
+		problems.Add(LabelProblem{label, fmt.Sprintf(format, args...)})
+	}
+
+	if len(t.RangeStarts) != 0x100 {
+		report("", "expected 256 bucket range starts, found %d", len(t.RangeStarts))
+	}
+
+	for bucket := 1; bucket < len(t.RangeStarts); bucket++ {
+		if t.RangeStarts[bucket] < t.RangeStarts[bucket - 1] {
+			report("", "range start of bucket %02x is before the one of bucket %02x", bucket, bucket - 1)
+		}
+	}
+
+	hash, hasHash := t.DetectHash()
+
+	names := make(map[string]int)
+	ids := make(map[int]string)
+	for i, label := range t.Labels {
+		if first, ok := names[label.Name]; ok {
+			report(label.Name, "duplicate of label %d", first)
+		} else {
+			names[label.Name] = i
+		}
+
+		if other, ok := ids[label.Id]; ok && other != label.Name {
+			report(label.Name, "id %08x is also used by #=%s", uint32(label.Id), other)
+		} else {
+			ids[label.Id] = label.Name
+		}
+
+		if hasHash {
+			if id, err := hash.Id(label.Name); err != nil {
+				report(label.Name, "cannot hash name: %v", err)
+			} else if id != label.Id {
+				report(label.Name, "id %08x is not the %s hash of the name, %08x", uint32(label.Id), hash.Name, uint32(id))
+			}
+		}
+
+		if len(t.RangeStarts) == 0x100 {
+			bucket := LabelBucket(label.Id)
+			start := t.RangeStarts[bucket]
+			end := len(t.Labels)
+			if bucket + 1 < len(t.RangeStarts) {
+				end = t.RangeStarts[bucket + 1]
+			}
+
+			if i < start || i >= end {
+				report(label.Name, "index %d is outside of bucket %02x range [%d, %d)", i, bucket, start, end)
+			}
+		}
+
+		if instructionCounts == nil {
+			continue
+		}
+
+		count, ok := instructionCounts[int(label.ScriptIndex)]
+		if !ok {
+			report(label.Name, "script %d does not exist", label.ScriptIndex)
+		} else if label.Offset < 0 || label.Offset > count {
+			report(label.Name, "offset %d is past the end of script %d (%d instructions)", label.Offset, label.ScriptIndex, count)
+		}
+	}
+
+	return problems.Items
+}
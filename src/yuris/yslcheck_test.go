@@ -0,0 +1,85 @@
+package yuris
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := newLabelTable(
+		Label{Name: "START", Id: 0x682d973f, Offset: 0, ScriptIndex: 0},
+		Label{Name: "MAIN", Id: 0x89bd20d0, Offset: 4, ScriptIndex: 1},
+	)
+
+	crc32 := LabelHashes[1]
+	otherId, _ := crc32.Id("OTHER")
+	endId, _ := crc32.Id("END")
+	mismatched := newHashedTable(crc32, "START", "MAIN")
+	mismatched.Labels = append(mismatched.Labels, Label{Name: "END", Id: otherId})
+	mismatched.Rebuild()
+
+	outsideBucket := newLabelTable(Label{Name: "A", Id: 0x10000000}, Label{Name: "B", Id: 0x20000000})
+	outsideBucket.Labels[1].Id = 0x05000000
+
+	tests := []struct {
+		name		string
+		table		LabelTable
+		counts		map[int]int
+		want		[]LabelProblem
+	}{
+		{
+			name:	"valid",
+			table:	valid,
+			counts:	map[int]int{0: 2, 1: 4},
+		},
+		{
+			name:	"offsets not checked",
+			table:	valid,
+		},
+		{
+			name:	"offset past the end",
+			table:	valid,
+			counts:	map[int]int{0: 2, 1: 3},
+			want:	[]LabelProblem{{"MAIN", "offset 4 is past the end of script 1 (3 instructions)"}},
+		},
+		{
+			name:	"missing script",
+			table:	valid,
+			counts:	map[int]int{0: 2},
+			want:	[]LabelProblem{{"MAIN", "script 1 does not exist"}},
+		},
+		{
+			name:	"id of another name",
+			table:	mismatched,
+			want:	[]LabelProblem{{"END", fmt.Sprintf("id %08x is not the crc32 hash of the name, %08x", uint32(otherId), uint32(endId))}},
+		},
+		{
+			name:	"duplicates",
+			table:	newLabelTable(Label{Name: "A", Id: 0x10000000}, Label{Name: "A", Id: 0x10000000}),
+			want:	[]LabelProblem{{"A", "duplicate of label 0"}},
+		},
+		{
+			name:	"shared id",
+			table:	newLabelTable(Label{Name: "A", Id: 0x10000000}, Label{Name: "B", Id: 0x10000000}),
+			want:	[]LabelProblem{{"B", "id 10000000 is also used by #=A"}},
+		},
+		{
+			name:	"outside of bucket",
+			table:	outsideBucket,
+			want:	[]LabelProblem{{"B", "index 1 is outside of bucket 05 range [0, 0)"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.table.Validate(test.counts)
+			if len(got) == 0 && len(test.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Validate() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
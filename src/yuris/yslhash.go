@@ -0,0 +1,123 @@
+package yuris
+
+import (
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"encoding/binary"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+)
+
+// A hash that may compute label ids from names.
+type LabelHash struct {
+	Name		string
+	Sum			func(name []byte) uint32
+}
+
+// Candidates for the hash the engine computes label ids with. The hash is not
+// documented, so a table is only hashed with a candidate that reproduces the
+// ids already stored in it.
+var LabelHashes = []LabelHash{
+	{"murmur2", murmur2},
+	{"crc32", crc32.ChecksumIEEE},
+	{"fnv1a", fnv1a},
+}
+
+// MurmurHash2 with a seed of 0.
+func murmur2(data []byte) uint32 {
+	const m = 0x5bd1e995
+
+	h := uint32(len(data))
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> 24
+		k *= m
+
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return h
+}
+
+func fnv1a(data []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(data)
+	return h.Sum32()
+}
+
+// Returns the label id of a name with the given hash. Names are hashed in the
+// encoding they are stored with.
+func (h LabelHash) Id(name string) (int, error) {
+	bs, err := utils.EncodeString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(h.Sum(bs)), nil
+}
+
+// Returns the number of labels whose id is the hash of their name.
+func (t *LabelTable) hashMatches(h LabelHash) int {
+	matches := 0
+	for _, label := range t.Labels {
+		if id, err := h.Id(label.Name); err == nil && id == label.Id {
+			matches++
+		}
+	}
+
+	return matches
+}
+
+// Returns the candidate hash that gives the stored id of the most labels, if
+// it does for more than half of them.
+func (t *LabelTable) DetectHash() (LabelHash, bool) {
+	best := LabelHash{}
+	bestMatches := 0
+	for _, h := range LabelHashes {
+		if matches := t.hashMatches(h); matches > bestMatches {
+			best, bestMatches = h, matches
+		}
+	}
+
+	return best, bestMatches * 2 > len(t.Labels)
+}
+
+// Returns the id of a new label name. It fails unless a candidate hash gives
+// the stored id of every label, since an id the engine does not compute
+// itself would make the label impossible to find.
+func (t *LabelTable) HashId(name string) (int, error) {
+	if len(t.Labels) == 0 {
+		return 0, fmt.Errorf("cannot tell the label hash of an empty table")
+	}
+
+	h, ok := t.DetectHash()
+	if !ok {
+		return 0, fmt.Errorf("label ids do not match a known name hash")
+	}
+
+	if matches := t.hashMatches(h); matches != len(t.Labels) {
+		return 0, fmt.Errorf("%d label ids do not match the %s hash of their name", len(t.Labels) - matches, h.Name)
+	}
+
+	return h.Id(name)
+}
@@ -0,0 +1,188 @@
+package yuris
+
+import (
+	"fmt"
+	"sort"
+	"io/ioutil"
+
+	"github.com/damianfadri/yuris-decompiler/utils"
+)
+
+// Returns the hash bucket of a label id.
+func LabelBucket(id int) int {
+	return int(uint32(id) >> 24)
+}
+
+func (t *LabelTable) Find(name string) int {
+	for i, label := range t.Labels {
+		if label.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Adds a label, computing its id with the hash the other labels use.
+func (t *LabelTable) Add(name string, scriptIndex int16, offset int) error {
+	if t.Find(name) >= 0 {
+		return fmt.Errorf("label %s already exists", name)
+	}
+
+	id, err := t.newId(name)
+	if err != nil {
+		return err
+	}
+
+	label := Label{}
+	label.Name = name
+	label.Id = id
+	label.Offset = offset
+	label.ScriptIndex = scriptIndex
+
+	t.Labels = append(t.Labels, label)
+	t.Rebuild()
+
+	return nil
+}
+
+// Renames a label and recomputes its id, which moves it to the hash bucket
+// of the new name.
+func (t *LabelTable) Rename(name string, newName string) error {
+	i := t.Find(name)
+	if i < 0 {
+		return fmt.Errorf("label %s does not exist", name)
+	}
+
+	if t.Find(newName) >= 0 {
+		return fmt.Errorf("label %s already exists", newName)
+	}
+
+	id, err := t.newId(newName)
+	if err != nil {
+		return err
+	}
+
+	t.Labels[i].Name = newName
+	t.Labels[i].Id = id
+	t.Rebuild()
+
+	return nil
+}
+
+// Returns the id of a new label name, if no other label uses it.
+func (t *LabelTable) newId(name string) (int, error) {
+	id, err := t.HashId(name)
+	if err != nil {
+		return 0, fmt.Errorf("cannot compute the id of %s: %w", name, err)
+	}
+
+	for _, label := range t.Labels {
+		if label.Id == id {
+			return 0, fmt.Errorf("id %08x of %s is already used by label %s", uint32(id), name, label.Name)
+		}
+	}
+
+	return id, nil
+}
+
+func (t *LabelTable) Move(name string, scriptIndex int16, offset int) error {
+	i := t.Find(name)
+	if i < 0 {
+		return fmt.Errorf("label %s does not exist", name)
+	}
+
+	t.Labels[i].ScriptIndex = scriptIndex
+	t.Labels[i].Offset = offset
+
+	return nil
+}
+
+// Sorts the labels by id and recomputes the start of each hash bucket.
+func (t *LabelTable) Rebuild() {
+	sort.SliceStable(t.Labels, func(i, j int) bool {
+		return uint32(t.Labels[i].Id) < uint32(t.Labels[j].Id)
+	})
+
+	t.RangeStarts = make([]int, 0x100)
+	i := 0
+	for bucket := 0; bucket < len(t.RangeStarts); bucket++ {
+		for i < len(t.Labels) && LabelBucket(t.Labels[i].Id) < bucket {
+			i++
+		}
+		t.RangeStarts[bucket] = i
+	}
+}
+
+// Returns an error if the labels are not sorted by id, or the range starts
+// are not the ones Rebuild computes. Editing such a table would change its
+// layout in a way the engine may not expect.
+func (t *LabelTable) CheckLayout() error {
+	rebuilt := LabelTable{Labels: make([]Label, len(t.Labels))}
+	copy(rebuilt.Labels, t.Labels)
+	rebuilt.Rebuild()
+
+	for i := range t.Labels {
+		if t.Labels[i] != rebuilt.Labels[i] {
+			return fmt.Errorf("label %s is not in id order", t.Labels[i].Name)
+		}
+	}
+
+	if len(t.RangeStarts) != len(rebuilt.RangeStarts) {
+		return fmt.Errorf("expected %d bucket range starts, found %d", len(rebuilt.RangeStarts), len(t.RangeStarts))
+	}
+
+	for bucket := range t.RangeStarts {
+		if t.RangeStarts[bucket] != rebuilt.RangeStarts[bucket] {
+			return fmt.Errorf("range start of bucket %02x is %d, expected %d", bucket, t.RangeStarts[bucket], rebuilt.RangeStarts[bucket])
+		}
+	}
+
+	return nil
+}
+
+func (t *LabelTable) Bytes() ([]byte, error) {
+	bw := utils.NewBinaryWriter()
+	bw.WriteBytes([]byte("YSLB")...)
+	bw.WriteInt32(t.Version)
+	bw.WriteInt32(len(t.Labels))
+
+	for bucket := 0; bucket < 0x100; bucket++ {
+		start := 0
+		if bucket < len(t.RangeStarts) {
+			start = t.RangeStarts[bucket]
+		}
+		bw.WriteInt32(start)
+	}
+
+	for _, label := range t.Labels {
+		name, err := utils.EncodeString(label.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(name) > 0xff {
+			return nil, fmt.Errorf("label %s is too long", label.Name)
+		}
+
+		bw.WriteBytes(byte(len(name)))
+		bw.WriteBytes(name...)
+		bw.WriteInt32(label.Id)
+		bw.WriteInt32(label.Offset)
+		bw.WriteInt16(label.ScriptIndex)
+
+		// Unused
+		bw.WriteInt16(0)
+	}
+
+	return bw.Bytes, nil
+}
+
+func WriteLabelTable(path string, t LabelTable) error {
+	data, err := t.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
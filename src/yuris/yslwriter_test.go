@@ -0,0 +1,195 @@
+package yuris
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func newLabelTable(labels ...Label) LabelTable {
+	table := LabelTable{Version: 494, Labels: labels}
+	table.Rebuild()
+
+	return table
+}
+
+func TestLabelTableBytes(t *testing.T) {
+	table := newLabelTable(
+		Label{Name: "MAIN", Id: 0x81000002, Offset: 3, ScriptIndex: 1},
+		Label{Name: "START", Id: 0x01000001, Offset: 0, ScriptIndex: 0},
+	)
+
+	data, err := table.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := bytes.NewBuffer(nil)
+	want.WriteString("YSLB")
+	binary.Write(want, binary.LittleEndian, []int32{494, 2})
+	for bucket := 0; bucket < 0x100; bucket++ {
+		start := int32(0)
+		switch {
+		case bucket > 0x81:
+			start = 2
+		case bucket > 0x01:
+			start = 1
+		}
+		binary.Write(want, binary.LittleEndian, start)
+	}
+	want.WriteByte(5)
+	want.WriteString("START")
+	binary.Write(want, binary.LittleEndian, []uint32{0x01000001, 0})
+	binary.Write(want, binary.LittleEndian, []int16{0, 0})
+	want.WriteByte(4)
+	want.WriteString("MAIN")
+	binary.Write(want, binary.LittleEndian, []uint32{0x81000002, 3})
+	binary.Write(want, binary.LittleEndian, []int16{1, 0})
+
+	if !bytes.Equal(data, want.Bytes()) {
+		t.Fatalf("Bytes() = %x\nwant      %x", data, want.Bytes())
+	}
+
+	parsed, err := ParseLabelTable(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, table) {
+		t.Errorf("round trip = %+v, want %+v", parsed, table)
+	}
+}
+
+// Builds a table whose ids are the given hash of the label names.
+func newHashedTable(h LabelHash, names ...string) LabelTable {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		id, _ := h.Id(name)
+		labels[i] = Label{Name: name, Id: id}
+	}
+
+	return newLabelTable(labels...)
+}
+
+func TestLabelTableEdits(t *testing.T) {
+	h := LabelHashes[0]
+	table := newHashedTable(h, "A", "C")
+
+	if err := table.Add("B", 2, 7); err != nil {
+		t.Fatal(err)
+	}
+	if i := table.Find("B"); i < 0 || table.Labels[i].Offset != 7 || table.Labels[i].ScriptIndex != 2 {
+		t.Errorf("added label = %+v", table.Labels)
+	}
+	if err := table.Add("A", 0, 0); err == nil {
+		t.Errorf("adding a label with a used name should fail")
+	}
+
+	if err := table.Rename("B", "RENAMED"); err != nil {
+		t.Fatal(err)
+	}
+	if i := table.Find("RENAMED"); i < 0 {
+		t.Errorf("renamed label not found")
+	} else if want, _ := h.Id("RENAMED"); table.Labels[i].Id != want {
+		t.Errorf("renamed label id = %08x, want %08x", uint32(table.Labels[i].Id), uint32(want))
+	}
+
+	if err := table.CheckLayout(); err != nil {
+		t.Errorf("CheckLayout() = %v after edits", err)
+	}
+	if matches := table.hashMatches(h); matches != len(table.Labels) {
+		t.Errorf("%d of %d ids match the hash after edits", matches, len(table.Labels))
+	}
+}
+
+func TestLabelTableEditsWithUnknownHash(t *testing.T) {
+	unknown := newLabelTable(Label{Name: "A", Id: 0x10000000}, Label{Name: "B", Id: 0x20000000})
+	if err := unknown.Rename("A", "RENAMED"); err == nil {
+		t.Errorf("renaming in a table with unknown ids should fail")
+	}
+	if err := unknown.Add("C", 0, 0); err == nil {
+		t.Errorf("adding to a table with unknown ids should fail")
+	}
+	if unknown.Find("A") != 0 || unknown.Find("C") >= 0 {
+		t.Errorf("failed edits changed the table: %+v", unknown.Labels)
+	}
+
+	// One label that does not match is enough to refuse computing ids.
+	mixed := newHashedTable(LabelHashes[0], "A", "B", "C")
+	mixed.Labels[0].Id ^= 1
+	if err := mixed.Rename("B", "RENAMED"); err == nil {
+		t.Errorf("renaming in a table with a mismatched id should fail")
+	}
+}
+
+func TestLabelHashes(t *testing.T) {
+	tests := []struct {
+		hash		string
+		name		string
+		want		uint32
+	}{
+		{"murmur2", "", 0x00000000},
+		{"murmur2", "START", 0x89f33553},
+		{"murmur2", "MAIN", 0x49981857},
+		// Names are hashed as Shift-JIS.
+		{"murmur2", "メイン", 0x9b4c94b4},
+		{"crc32", "START", 0x682d973f},
+	}
+
+	for _, test := range tests {
+		for _, h := range LabelHashes {
+			if h.Name != test.hash {
+				continue
+			}
+
+			id, err := h.Id(test.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uint32(id) != test.want {
+				t.Errorf("%s(%q) = %08x, want %08x", test.hash, test.name, uint32(id), test.want)
+			}
+		}
+	}
+}
+
+func TestDetectHash(t *testing.T) {
+	for _, h := range LabelHashes {
+		table := newHashedTable(h, "START", "MAIN", "END")
+		if detected, ok := table.DetectHash(); !ok || detected.Name != h.Name {
+			t.Errorf("DetectHash() = %s, %v, want %s", detected.Name, ok, h.Name)
+		}
+	}
+
+	unknown := newLabelTable(Label{Name: "A", Id: 0x10000000})
+	if _, ok := unknown.DetectHash(); ok {
+		t.Errorf("DetectHash() found a hash for made up ids")
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	unsorted := newLabelTable(Label{Name: "A", Id: 0x10000000}, Label{Name: "B", Id: 0x20000000})
+	unsorted.Labels[0], unsorted.Labels[1] = unsorted.Labels[1], unsorted.Labels[0]
+
+	badRange := newLabelTable(Label{Name: "A", Id: 0x10000000})
+	badRange.RangeStarts[0x05] = 1
+
+	tests := []struct {
+		name		string
+		table		LabelTable
+		ok			bool
+	}{
+		{"rebuilt", newLabelTable(Label{Name: "A", Id: 0x10000000}, Label{Name: "B", Id: 0x20000000}), true},
+		{"empty", newLabelTable(), true},
+		{"unsorted", unsorted, false},
+		{"range start", badRange, false},
+		{"missing range starts", LabelTable{}, false},
+	}
+
+	for _, test := range tests {
+		err := test.table.CheckLayout()
+		if (err == nil) != test.ok {
+			t.Errorf("%s: CheckLayout() = %v, want ok %v", test.name, err, test.ok)
+		}
+	}
+}
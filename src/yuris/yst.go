@@ -2,7 +2,6 @@ package yuris
 
 import (
 	"io/ioutil"
-	"log"
 	"fmt"
 
 	"github.com/damianfadri/yuris-decompiler/utils"
@@ -21,43 +20,74 @@ type Command struct {
 	Id					byte
 	NumAttributes		byte
 	Offset				byte
+	AttributeIndex		int
+}
+
+type Header struct {
+	Version					int		`json:"version"`
+	NumInstructions			int		`json:"numInstructions"`
+	InstructionsSize		int		`json:"instructionsSize"`
+	AttrDescriptorsSize		int		`json:"attrDescriptorsSize"`
+	AttrValuesSize			int		`json:"attrValuesSize"`
+	LineNumbersSize			int		`json:"lineNumbersSize"`
+
+	// Key used to decrypt the script, or 0 if it is not encrypted.
+	Key						uint32	`json:"key"`
 }
 
 type Script struct {
+	Header		Header
 	Commands 	[]Command
 	Attributes	[]Attribute
 }
 
-func ReadYST(path string) Script {
+// Reads a compiled script, deriving the decryption key from the script.
+func ReadYST(path string) (Script, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatal(err)
+		return Script{}, err
 	}
 
+	return ParseYST(data)
+}
+
+// Parses a compiled script, deriving the decryption key from the script.
+func ParseYST(data []byte) (Script, error) {
+	return parseYST(data, 0, false)
+}
+
+// Parses a compiled script encrypted with the given key.
+func ParseYSTWithKey(data []byte, key uint32) (Script, error) {
+	return parseYST(data, key, true)
+}
+
+func parseYST(data []byte, key uint32, hasKey bool) (script Script, err error) {
+	defer recoverFormat("yst", &err)
+
 	br := utils.NewBinaryReader(data)
 
 	magic := br.ReadString(4)
 	if magic != "YSTB" {
-		log.Fatal("Invalid magic in ysc.ybn")
+		return script, formatError("invalid magic in yst.ybn")
 	}
 
 	// YU-RIS version
-	_ = br.ReadInt32()
+	script.Header.Version = br.ReadInt32()
 
 	// Number of instructions
 	numInstructions := br.ReadInt32()
 	szInstructions := br.ReadInt32()
 	if (szInstructions != numInstructions * 4) {
-		log.Fatal("Instruction size does not match instruction count.")
+		return script, formatError("instruction size does not match instruction count")
 	}
 
 	szAttrDescriptors := br.ReadInt32()
 
-	// Attribute values size
-	_ = br.ReadInt32()
-
-	// Line numbers size
-	_ = br.ReadInt32()
+	script.Header.NumInstructions = numInstructions
+	script.Header.InstructionsSize = szInstructions
+	script.Header.AttrDescriptorsSize = szAttrDescriptors
+	script.Header.AttrValuesSize = br.ReadInt32()
+	script.Header.LineNumbersSize = br.ReadInt32()
 
 	br.Skip(4)
 
@@ -65,16 +95,16 @@ func ReadYST(path string) Script {
 	offsetAttrDescriptors := offsetInstructions + szInstructions
 	offsetAttrValues := offsetAttrDescriptors + szAttrDescriptors
 
-	key := uint32(0)
-	if (szAttrDescriptors > 0) {
+	if (!hasKey && szAttrDescriptors > 0) {
 		br.Seek(offsetAttrDescriptors + 8)
 		key = uint32(br.ReadInt32())
 	}
 
 	// Decrypt script data if possible.
-	decrypt(br, key)
-
-	script := Script{}
+	if err := decrypt(br, key); err != nil {
+		return script, err
+	}
+	script.Header.Key = key
 
 	br = utils.NewBinaryReader(data)
 
@@ -92,6 +122,7 @@ func ReadYST(path string) Script {
 	} 
 
 	commands := dsa.NewList[Command]()
+	attributeIndex := 0
 	br.Seek(offsetInstructions)
 	for br.Position < offsetAttrDescriptors {
 		command := Command{}
@@ -100,25 +131,45 @@ func ReadYST(path string) Script {
 		command.Offset = br.ReadByte()
 		br.Skip(1)
 
+		// Attributes are stored in command order.
+		command.AttributeIndex = attributeIndex
+		attributeIndex += int(command.NumAttributes)
+
 		commands.Add(command)
 	}
 
 	script.Attributes = attributes.Items
 	script.Commands = commands.Items
 
-	return script
+	return script, nil
+}
+
+// Returns the attributes belonging to the given command.
+func (script *Script) CommandAttributes(command Command) []Attribute {
+	start := command.AttributeIndex
+	end := start + int(command.NumAttributes)
+	if end > len(script.Attributes) {
+		end = len(script.Attributes)
+	}
+
+	return script.Attributes[start:end]
 }
 
 func (attr *Attribute) Decompile() string {
+	return attr.DecompileWith(nil)
+}
+
+// Decompiles the attribute value, naming variables with the given symbols.
+func (attr *Attribute) DecompileWith(symbols *Symbols) string {
 	stack := dsa.NewStack[string]()
 	br := utils.NewBinaryReader(attr.Bytes)
 
-	decompileAttribute(br, stack)
+	decompileAttribute(br, stack, symbols)
 
 	return stack.Pop()
 }
 
-func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string]) {
+func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string], symbols *Symbols) {
 	if len(br.Bytes) == br.Position {
 		return;
 	}
@@ -212,7 +263,7 @@ func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string]) {
 		result := fmt.Sprintf("%s & %s", first, second)
 		stack.Push(result)
 	case 0x42:	// int8
-		number := br.ReadByte()
+		number := int8(br.ReadByte())
 
 		result := fmt.Sprintf("%d", number)
 		stack.Push(result)
@@ -225,10 +276,10 @@ func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string]) {
 		prefix := br.ReadString(1)
 		varId := br.ReadInt16()
 
-		result := fmt.Sprintf("%svar%x", prefix, varId)
+		result := symbols.VariableName(prefix, varId)
 		stack.Push(result)
 	case 0x49:	// int32
-		number := br.ReadInt32()
+		number := int32(br.ReadInt32())
 
 		result := fmt.Sprintf("%d", number)
 		stack.Push(result)
@@ -260,7 +311,7 @@ func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string]) {
 		prefix := br.ReadString(1)
 		varId := br.ReadInt16()
 
-		result := fmt.Sprintf("%svar%x", prefix, varId)
+		result := symbols.VariableName(prefix, varId)
 		stack.Push(result)
 		stack.Push("(")
 	case 0x57:	// int16
@@ -294,7 +345,7 @@ func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string]) {
 		prefix := br.ReadString(1)
 		varId := br.ReadInt16()
 
-		result := fmt.Sprintf("%svar%x()", prefix, varId)
+		result := fmt.Sprintf("%s()", symbols.VariableName(prefix, varId))
 		stack.Push(result)
 	case 0x7c:	// logical or
 		second := stack.Pop()
@@ -304,12 +355,12 @@ func decompileAttribute(br *utils.BinaryReader, stack *dsa.Stack[string]) {
 		stack.Push(result)
 	}
 
-	decompileAttribute(br, stack)
+	decompileAttribute(br, stack, symbols)
 }
 
-func decrypt(br *utils.BinaryReader, key uint32) {
+func decrypt(br *utils.BinaryReader, key uint32) error {
 	if (key == 0) {
-		return;
+		return nil
 	}
 
 	repeatedKey := []byte{
@@ -328,14 +379,18 @@ func decrypt(br *utils.BinaryReader, key uint32) {
 		br.Seek(offsetSize)
 		size := br.ReadInt32()
 
-		xor(br, offsetData, size, repeatedKey)
+		if err := xor(br, offsetData, size, repeatedKey); err != nil {
+			return err
+		}
 		offsetData += size
 	}
+
+	return nil
 }
 
-func xor(br *utils.BinaryReader, offset int, length int, key []byte) {
+func xor(br *utils.BinaryReader, offset int, length int, key []byte) error {
 	if (offset < 0 || length < 0 || offset + length > len(br.Bytes)) {
-		log.Fatal("Script data cannot be decrypted with given key.")
+		return formatError("script data cannot be decrypted with given key")
 	}
 
 	offsetKey := 0
@@ -360,6 +415,8 @@ func xor(br *utils.BinaryReader, offset int, length int, key []byte) {
 			offsetKey = 0
 		}
 	}
+
+	return nil
 }
 
 func min(a, b int) int {
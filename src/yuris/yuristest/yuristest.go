@@ -0,0 +1,85 @@
+// Package yuristest builds scripts in memory for tests of the packages that
+// decompile and analyze them.
+package yuristest
+
+import (
+	"bytes"
+
+	"github.com/damianfadri/yuris-decompiler/yuris"
+)
+
+// A command of a test script, with the raw value of each attribute.
+type CommandSpec struct {
+	Name		string
+	Attributes	[][]byte
+}
+
+func Command(name string, attributes ...[]byte) CommandSpec {
+	return CommandSpec{name, attributes}
+}
+
+// Returns an RPN instruction with its operand.
+func Token(opcode byte, operand ...byte) []byte {
+	return append([]byte{opcode, byte(len(operand)), byte(len(operand) >> 8)}, operand...)
+}
+
+// Joins RPN instructions into an attribute value.
+func Expr(tokens ...[]byte) []byte {
+	return bytes.Join(tokens, nil)
+}
+
+// Returns a string literal that references a label, as GOTO and GOSUB use.
+func LabelRef(name string) []byte {
+	return Token(0x4d, []byte("\"#" + name + "\"")...)
+}
+
+// Returns a reference to the @ variable with the given id.
+func Variable(id int16) []byte {
+	return Token(0x48, '@', byte(id), byte(id >> 8))
+}
+
+// Attribute names of the commands of test scripts, by position.
+var AttributeNames = map[string][]string{
+	"IF":		{"CONDITION"},
+	"ELSE":		{"CONDITION"},
+	"LOOP":		{"SET"},
+	"GOSUB":	{"PLABEL"},
+	"GOTO":		{"PLABEL"},
+	"LET":		{"TARGET", "VALUE"},
+}
+
+// Builds a script and the compiler definition of its commands. Command ids
+// are given in order of first use, and attribute ids by position.
+func NewScript(commands ...CommandSpec) (yuris.Script, yuris.CompilerDefinition) {
+	script := yuris.Script{}
+	compiler := yuris.CompilerDefinition{Commands: make(map[byte]string)}
+	ids := make(map[string]byte)
+
+	for _, c := range commands {
+		id, ok := ids[c.Name]
+		if !ok {
+			id = byte(len(ids))
+			ids[c.Name] = id
+			compiler.Commands[id] = c.Name
+
+			names := make(map[byte]string)
+			for i, name := range AttributeNames[c.Name] {
+				names[byte(i)] = name
+			}
+			compiler.Attributes = append(compiler.Attributes, names)
+			compiler.Definitions = append(compiler.Definitions, make(map[byte]yuris.AttributeDefinition))
+		}
+
+		script.Commands = append(script.Commands, yuris.Command{
+			Id:				id,
+			NumAttributes:	byte(len(c.Attributes)),
+			AttributeIndex:	len(script.Attributes),
+		})
+
+		for i, bs := range c.Attributes {
+			script.Attributes = append(script.Attributes, yuris.Attribute{Id: int16(i), Type: make([]byte, 2), Bytes: bs})
+		}
+	}
+
+	return script, compiler
+}